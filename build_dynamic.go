@@ -3,14 +3,33 @@
 
 package rti
 
+// The native libraries are linked from rticonnextdds-connector/lib/<platform>
+// in this module, where cmd/download-libs installs them. On Linux the binaries
+// also embed an $ORIGIN rpath, so that the libraries are found next to the
+// binary, where download-libs -install-to places them, without
+// LD_LIBRARY_PATH. No path of the build machine is embedded: elsewhere, e.g.
+// to run binaries built inside this module, set LD_LIBRARY_PATH (or
+// DYLD_LIBRARY_PATH on macOS), or opt in to an rpath with CGO_LDFLAGS, which
+// is also how modules installed with go get, whose read-only module cache has
+// no libraries, are linked:
+//
+//	export CGO_LDFLAGS="-L$(pwd)/rticonnextdds-connector/lib/linux-x64 -Wl,-rpath,$(pwd)/rticonnextdds-connector/lib/linux-x64"
+//
+// The linux,arm64 and linux,arm targets can be cross-compiled from an amd64
+// host with a cross C toolchain, after installing their libraries:
+//
+//	go run ./cmd/download-libs -platform linux-x64,linux-arm64,linux-arm
+//	CGO_ENABLED=1 GOOS=linux GOARCH=arm64 CC=aarch64-linux-gnu-gcc go build ./...
+//	CGO_ENABLED=1 GOOS=linux GOARCH=arm GOARM=7 CC=arm-linux-gnueabihf-gcc go build ./...
+
 /*
 #cgo CFLAGS: -I${SRCDIR}/include -I${SRCDIR}/rticonnextdds-connector/include
 #cgo linux,amd64 CFLAGS: -DRTI_UNIX -DRTI_LINUX -DRTI_64BIT
-#cgo linux,amd64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector/lib/linux-x64 -lrtiddsconnector -ldl -lm -lpthread -lrt
+#cgo linux,amd64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector/lib/linux-x64 -Wl,-rpath,$ORIGIN -lrtiddsconnector -ldl -lm -lpthread -lrt
 #cgo linux,arm64 CFLAGS: -DRTI_UNIX -DRTI_LINUX -DRTI_64BIT
-#cgo linux,arm64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector/lib/linux-arm64 -lrtiddsconnector -ldl -lm -lpthread -lrt
+#cgo linux,arm64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector/lib/linux-arm64 -Wl,-rpath,$ORIGIN -lrtiddsconnector -ldl -lm -lpthread -lrt
 #cgo linux,arm CFLAGS: -DRTI_UNIX -DRTI_LINUX
-#cgo linux,arm LDFLAGS: -L${SRCDIR}/rticonnextdds-connector/lib/linux-arm -lrtiddsconnector -ldl -lm -lpthread -lrt
+#cgo linux,arm LDFLAGS: -L${SRCDIR}/rticonnextdds-connector/lib/linux-arm -Wl,-rpath,$ORIGIN -lrtiddsconnector -ldl -lm -lpthread -lrt
 #cgo darwin,amd64 CFLAGS: -DRTI_UNIX -DRTI_DARWIN -DRTI_DARWIN10 -DRTI_64BIT -m64
 #cgo darwin,amd64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector/lib/osx-x64 -lrtiddsconnector -ldl -lm -lpthread
 #cgo darwin,arm64 CFLAGS: -DRTI_UNIX -DRTI_DARWIN -DRTI_DARWIN10 -DRTI_64BIT
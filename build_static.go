@@ -0,0 +1,33 @@
+//go:build static && !windows
+// +build static,!windows
+
+package rti
+
+// The static build links the static Connector archives installed by
+// download-libs -static in rticonnextdds-connector-static/lib/<platform>, so
+// that binaries need no shared Connector library at runtime. On Linux, a fully
+// static binary, e.g. for a scratch container, is built with:
+//
+//	go build -tags static -ldflags '-linkmode external -extldflags "-static"'
+//
+// The archives are librtiddsconnectorz, libnddscz and libnddscorez, which
+// download-libs -static checks are in the installed archive. Windows is not
+// supported: see build_static_windows.go.
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/include -I${SRCDIR}/rticonnextdds-connector/include
+#cgo linux,amd64 CFLAGS: -DRTI_UNIX -DRTI_LINUX -DRTI_64BIT
+#cgo linux,amd64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector-static/lib/linux-x64 -lrtiddsconnectorz -lnddscz -lnddscorez -ldl -lm -lpthread -lrt
+#cgo linux,arm64 CFLAGS: -DRTI_UNIX -DRTI_LINUX -DRTI_64BIT
+#cgo linux,arm64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector-static/lib/linux-arm64 -lrtiddsconnectorz -lnddscz -lnddscorez -ldl -lm -lpthread -lrt
+#cgo linux,arm CFLAGS: -DRTI_UNIX -DRTI_LINUX
+#cgo linux,arm LDFLAGS: -L${SRCDIR}/rticonnextdds-connector-static/lib/linux-arm -lrtiddsconnectorz -lnddscz -lnddscorez -ldl -lm -lpthread -lrt
+#cgo darwin,amd64 CFLAGS: -DRTI_UNIX -DRTI_DARWIN -DRTI_DARWIN10 -DRTI_64BIT -m64
+#cgo darwin,amd64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector-static/lib/osx-x64 -lrtiddsconnectorz -lnddscz -lnddscorez -ldl -lm -lpthread
+#cgo darwin,arm64 CFLAGS: -DRTI_UNIX -DRTI_DARWIN -DRTI_DARWIN10 -DRTI_64BIT
+#cgo darwin,arm64 LDFLAGS: -L${SRCDIR}/rticonnextdds-connector-static/lib/osx-arm64 -lrtiddsconnectorz -lnddscz -lnddscorez -ldl -lm -lpthread
+
+#include "rticonnextdds-connector.h"
+#include <stdlib.h>
+*/
+import "C"
@@ -0,0 +1,18 @@
+//go:build static && windows
+// +build static,windows
+
+package rti
+
+// The static build is not supported on Windows, where the Connector is only
+// distributed as DLLs: building with -tags static fails with the error below
+// rather than with unresolved symbols. Build without the static tag and ship
+// the DLLs installed by download-libs next to the binary instead.
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/include -I${SRCDIR}/rticonnextdds-connector/include
+#error "static linking (-tags static) is not supported on Windows: build without the static tag"
+
+#include "rticonnextdds-connector.h"
+#include <stdlib.h>
+*/
+import "C"
@@ -0,0 +1,292 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package rti implements functions of RTI Connector for Connext DDS in Go
+package rti
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+/********
+* Types *
+*********/
+
+// LastValueCache holds the last value of every instance of an input, keyed
+// by the values of the key members of its type (key="true" in the XML type
+// definition). Instances are removed when they are disposed or lose all
+// their writers.
+//
+// Update and Run feed the cache from the input, which must not be used
+// otherwise; they and Get, Snapshot and Changes can be called from any
+// goroutine.
+type LastValueCache[T any] struct {
+	input *Input
+	// key members of the type of the input
+	keys []xmlMember
+	// updateMu serializes Update, so that the samples of one Take are
+	// applied before the next Take
+	updateMu sync.Mutex
+
+	mu     sync.RWMutex
+	values map[string]T
+
+	subscribersMu sync.Mutex
+	subscribers   map[*cacheSubscriber[T]]struct{}
+}
+
+// CacheChange is a change of an instance of a LastValueCache, reported by
+// LastValueCache.Changes. Value is the removed value when Removed is set.
+type CacheChange[T any] struct {
+	Key     string
+	Value   T
+	Removed bool
+}
+
+type cacheSubscriber[T any] struct {
+	ctx     context.Context
+	changes chan CacheChange[T]
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// NewLastValueCache is a constructor of LastValueCache for an input, e.g.:
+//
+//	cache, err := rti.NewLastValueCache[types.Shape](input)
+//
+// The key members are read from the type of the input in the XML
+// configuration of its connector.
+func NewLastValueCache[T any](input *Input) (*LastValueCache[T], error) {
+	if input == nil {
+		return nil, errors.New("input is null")
+	}
+
+	keys, err := input.typeKeyMembers()
+	if err != nil {
+		return nil, err
+	}
+
+	return &LastValueCache[T]{
+		input:       input,
+		keys:        keys,
+		values:      make(map[string]T),
+		subscribers: make(map[*cacheSubscriber[T]]struct{}),
+	}, nil
+}
+
+// Update is a function to take the samples available in the input and
+// apply them to the cache. Its calls into the native layer are serialized
+// with the other calls into the connector, so it may be called while the
+// connector is used by other goroutines, or while Run runs.
+func (cache *LastValueCache[T]) Update() error {
+	if cache == nil {
+		return errors.New("cache is null")
+	}
+
+	cache.updateMu.Lock()
+	defer cache.updateMu.Unlock()
+
+	if err := cache.input.Take(); err != nil {
+		if err == ErrNoData {
+			return nil
+		}
+		return err
+	}
+	changes, err := cache.apply()
+	cache.notify(changes)
+
+	return err
+}
+
+// Run updates the cache every time data is available in the input, until
+// ctx is done. Run returns the context error once ctx is done, or the first
+// error updating the cache. Its waits are serialized with the other calls
+// into the connector, like Update, and last a few milliseconds so as not to
+// delay them. While it runs, the participant of the connector cannot be
+// created again, see Connector.Watch.
+func (cache *LastValueCache[T]) Run(ctx context.Context) error {
+	if cache == nil {
+		return errors.New("cache is null")
+	}
+	release := cache.input.connector.watch()
+	defer release()
+
+	return poll(ctx, cache.input.Wait, cache.Update)
+}
+
+// Get returns the last value of the instance with the given key values, in
+// the order of the key members of the type, e.g. cache.Get("BLUE")
+func (cache *LastValueCache[T]) Get(key ...interface{}) (T, bool) {
+	var value T
+	if cache == nil {
+		return value, false
+	}
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	value, ok := cache.values[cacheKey(key)]
+	return value, ok
+}
+
+// Snapshot returns a copy of the last values of all the instances, keyed
+// as CacheChange.Key: the key value for a single key member, e.g. "BLUE",
+// and a JSON array of the key values otherwise, e.g. ["BLUE","square"]
+func (cache *LastValueCache[T]) Snapshot() map[string]T {
+	if cache == nil {
+		return nil
+	}
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return maps.Clone(cache.values)
+}
+
+// Changes returns a channel reporting the changes of the cache made by
+// Update. The channel is closed when ctx is done. Update blocks until every
+// change is received, so the channel must be drained until ctx is done.
+func (cache *LastValueCache[T]) Changes(ctx context.Context) <-chan CacheChange[T] {
+	subscriber := &cacheSubscriber[T]{ctx: ctx, changes: make(chan CacheChange[T])}
+	if cache == nil {
+		close(subscriber.changes)
+		return subscriber.changes
+	}
+
+	cache.subscribersMu.Lock()
+	cache.subscribers[subscriber] = struct{}{}
+	cache.subscribersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		cache.subscribersMu.Lock()
+		delete(cache.subscribers, subscriber)
+		cache.subscribersMu.Unlock()
+		close(subscriber.changes)
+	}()
+
+	return subscriber.changes
+}
+
+/********************
+* Private Functions *
+********************/
+
+// apply is a function to apply the samples of the last Take to the cache and
+// return the changes
+func (cache *LastValueCache[T]) apply() ([]CacheChange[T], error) {
+	length, err := cache.input.Samples.GetLength()
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var changes []CacheChange[T]
+	for i := 0; i < length; i++ {
+		valid, err := cache.input.Infos.IsValid(i)
+		if err != nil {
+			return changes, err
+		}
+		instanceState, err := cache.input.Infos.GetInstanceState(i)
+		if err != nil {
+			return changes, err
+		}
+		// The key members of samples without valid data are still set
+		jsonData, err := cache.input.Samples.GetJSON(i)
+		if err != nil {
+			return changes, err
+		}
+		key, err := jsonKey(jsonData, cache.keys)
+		if err != nil {
+			return changes, err
+		}
+
+		switch {
+		case instanceState == "NOT_ALIVE_DISPOSED" || instanceState == "NOT_ALIVE_NO_WRITERS":
+			if value, ok := cache.values[key]; ok {
+				delete(cache.values, key)
+				changes = append(changes, CacheChange[T]{Key: key, Value: value, Removed: true})
+			}
+		case valid:
+			var value T
+			if err := json.Unmarshal(jsonData, &value); err != nil {
+				return changes, err
+			}
+			cache.values[key] = value
+			changes = append(changes, CacheChange[T]{Key: key, Value: value})
+		}
+	}
+
+	return changes, nil
+}
+
+// notify is a function to send changes to the subscribers of the cache
+func (cache *LastValueCache[T]) notify(changes []CacheChange[T]) {
+	if len(changes) == 0 {
+		return
+	}
+
+	cache.subscribersMu.Lock()
+	defer cache.subscribersMu.Unlock()
+	for subscriber := range cache.subscribers {
+		for _, change := range changes {
+			select {
+			case subscriber.changes <- change:
+			case <-subscriber.ctx.Done():
+			}
+		}
+	}
+}
+
+// jsonKey is a function to return the key of the JSON sample jsonData, made
+// of the values of its key members
+func jsonKey(jsonData []byte, keys []xmlMember) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var members map[string]interface{}
+	if err := decoder.Decode(&members); err != nil {
+		return "", err
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, ok := members[key.Name]
+		if !ok {
+			return "", fmt.Errorf("sample has no key member %q", key.Name)
+		}
+		values[i] = value
+	}
+	return cacheKey(values), nil
+}
+
+// cacheKey is a function to format key values as a key of a LastValueCache.
+// The values are formatted alike whatever their Go type, e.g. 1 and
+// json.Number("1"). Several values are encoded as a JSON array, so that no
+// value can be mistaken for a separator.
+func cacheKey(values []interface{}) string {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = fmt.Sprint(value)
+	}
+	if len(formatted) == 1 {
+		return formatted[0]
+	}
+	// Encoding strings cannot fail
+	key, _ := json.Marshal(formatted)
+	return string(key)
+}
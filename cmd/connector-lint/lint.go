@@ -0,0 +1,358 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// basicTypes are the type names of members that do not refer to a type of
+// the configuration
+var basicTypes = map[string]bool{
+	"boolean": true, "char": true, "char8": true, "char16": true, "wchar": true,
+	"octet": true, "byte": true, "int8": true, "uint8": true,
+	"short": true, "int16": true, "unsignedShort": true, "uint16": true,
+	"long": true, "int32": true, "unsignedLong": true, "uint32": true,
+	"longLong": true, "int64": true, "unsignedLongLong": true, "uint64": true,
+	"float": true, "float32": true, "double": true, "float64": true,
+	"longDouble": true, "float128": true, "string": true, "wstring": true,
+}
+
+// typeElements are the elements of <types> defining a named type
+var typeElements = map[string]bool{
+	"struct": true, "union": true, "enum": true, "typedef": true,
+	"valuetype": true, "sparse_valuetype": true, "bitmask": true, "bitset": true,
+}
+
+// linter holds the definitions of a configuration, by fully qualified name
+type linter struct {
+	issues       []Issue
+	types        map[string]*element
+	qosProfiles  map[string]*element
+	domains      map[string]*element
+	participants map[string]*element
+}
+
+// lintFiles parses the files of a configuration and returns its issues, by
+// file and position
+func lintFiles(files []string) []Issue {
+	var roots []*element
+	var issues []Issue
+	for _, file := range files {
+		root, parseIssues := parseFile(file, nil)
+		issues = append(issues, parseIssues...)
+		if root != nil {
+			roots = append(roots, root)
+		}
+	}
+
+	issues = append(issues, lint(roots)...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+
+	return issues
+}
+
+// lint checks the <dds> elements of a configuration
+func lint(roots []*element) []Issue {
+	l := &linter{
+		types:        make(map[string]*element),
+		qosProfiles:  make(map[string]*element),
+		domains:      make(map[string]*element),
+		participants: make(map[string]*element),
+	}
+
+	for _, root := range roots {
+		l.collect(root)
+		l.checkDuplicates(root)
+	}
+	for _, root := range roots {
+		for _, child := range root.children {
+			switch child.name {
+			case "types":
+				l.checkTypes(child, "")
+			case "qos_library":
+				l.checkQoS(child, child.attr("name"))
+			case "domain_library":
+				for _, domain := range children(child, "domain") {
+					l.checkDomain(domain, child.attr("name"))
+				}
+			case "domain_participant_library":
+				for _, participant := range children(child, "domain_participant") {
+					l.checkParticipant(participant, child.attr("name"))
+				}
+			}
+		}
+	}
+
+	return l.issues
+}
+
+// children returns the children of e named name
+func children(e *element, name string) []*element {
+	var result []*element
+	for _, child := range e.children {
+		if child.name == name {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// collect records the types, QoS profiles, domains and participants defined
+// by a <dds> element
+func (l *linter) collect(root *element) {
+	for _, child := range root.children {
+		library := child.attr("name")
+		switch child.name {
+		case "types":
+			l.collectTypes(child, "")
+		case "qos_library":
+			for _, profile := range children(child, "qos_profile") {
+				l.qosProfiles[library+"::"+profile.attr("name")] = profile
+			}
+		case "domain_library":
+			for _, domain := range children(child, "domain") {
+				l.domains[library+"::"+domain.attr("name")] = domain
+			}
+		case "domain_participant_library":
+			for _, participant := range children(child, "domain_participant") {
+				l.participants[library+"::"+participant.attr("name")] = participant
+			}
+		}
+	}
+}
+
+// collectTypes records the types of a <types> or <module> element, whose
+// fully qualified name is scope
+func (l *linter) collectTypes(e *element, scope string) {
+	for _, child := range e.children {
+		switch {
+		case child.name == "module":
+			l.collectTypes(child, scope+child.attr("name")+"::")
+		case typeElements[child.name]:
+			l.types[scope+child.attr("name")] = child
+		}
+	}
+}
+
+// checkDuplicates reports sibling elements of the same kind with the same name
+func (l *linter) checkDuplicates(e *element) {
+	first := make(map[string]*element)
+	for _, child := range e.children {
+		if name := child.attr("name"); name != "" {
+			key := child.name + "\x00" + name
+			if previous, ok := first[key]; ok {
+				l.issues = append(l.issues, child.issue("duplicate %s %q, first defined at %s",
+					child.name, name, previous.position()))
+			} else {
+				first[key] = child
+			}
+		}
+		l.checkDuplicates(child)
+	}
+}
+
+// resolveType returns whether ref names a type from the module scope, looking
+// it up in the enclosing modules as IDL does
+func (l *linter) resolveType(ref, scope string) bool {
+	if name, ok := strings.CutPrefix(ref, "::"); ok {
+		return l.types[name] != nil
+	}
+	for {
+		if l.types[scope+ref] != nil {
+			return true
+		}
+		if scope == "" {
+			return false
+		}
+		scope = strings.TrimSuffix(scope, "::")
+		if i := strings.LastIndex(scope, "::"); i >= 0 {
+			scope = scope[:i+2]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// checkTypes checks the type references of a <types> or <module> element,
+// whose fully qualified name is scope
+func (l *linter) checkTypes(e *element, scope string) {
+	for _, child := range e.children {
+		switch child.name {
+		case "module":
+			l.checkTypes(child, scope+child.attr("name")+"::")
+		case "member", "typedef", "discriminator":
+			l.checkTypeRef(child, scope)
+		default:
+			if !typeElements[child.name] {
+				continue
+			}
+			if base := child.attr("baseType"); base != "" && !l.resolveType(base, scope) {
+				l.issues = append(l.issues, child.issue("%s %q has unknown base type %q", child.name, child.attr("name"), base))
+			}
+			if child.name == "typedef" {
+				l.checkTypeRef(child, scope)
+			}
+			l.checkTypes(child, scope)
+		}
+	}
+}
+
+// checkTypeRef checks the type of a member, typedef or union discriminator
+func (l *linter) checkTypeRef(e *element, scope string) {
+	ref := e.attr("type")
+	if ref == "nonBasic" {
+		ref = e.attr("nonBasicTypeName")
+	}
+	if ref == "" || basicTypes[ref] {
+		return
+	}
+	if !l.resolveType(ref, scope) {
+		l.issues = append(l.issues, e.issue("%s %q has unknown type %q", e.name, e.attr("name"), ref))
+	}
+}
+
+// resolveQoSProfile returns whether ref names a QoS profile, relative to the
+// QoS library library if it is not qualified. Built-in profiles always
+// resolve.
+func (l *linter) resolveQoSProfile(ref, library string) bool {
+	if strings.HasPrefix(ref, "Builtin") {
+		return true
+	}
+	if !strings.Contains(ref, "::") {
+		ref = library + "::" + ref
+	}
+	return l.qosProfiles[ref] != nil
+}
+
+// checkQoS checks the QoS profile references of e and its descendants, within
+// the QoS library library (empty outside QoS libraries)
+func (l *linter) checkQoS(e *element, library string) {
+	for _, child := range e.children {
+		if child.name == "qos_profile" || strings.HasSuffix(child.name, "_qos") {
+			if base := child.attr("base_name"); base != "" && !l.resolveQoSProfile(base, library) {
+				l.issues = append(l.issues, child.issue("%s references unknown QoS profile %q", child.name, base))
+			}
+		}
+		if child.name == "base_name" && e.name == "qos_profile" {
+			for _, base := range children(child, "element") {
+				if ref := strings.TrimSpace(base.text); !l.resolveQoSProfile(ref, library) {
+					l.issues = append(l.issues, base.issue("qos_profile references unknown QoS profile %q", ref))
+				}
+			}
+			continue
+		}
+		l.checkQoS(child, library)
+	}
+}
+
+// registeredTypes returns the names of the types registered by a domain or a
+// participant, and the topics they define, including the ones of their base
+func registeredTypes(e *element, bases map[string]*element, types, topics map[string]bool) {
+	seen := make(map[*element]bool)
+	for e != nil && !seen[e] {
+		seen[e] = true
+		for _, registerType := range children(e, "register_type") {
+			types[registerType.attr("name")] = true
+		}
+		for _, topic := range children(e, "topic") {
+			topics[topic.attr("name")] = true
+		}
+		e = bases[qualify(e.attr("base_name"), e.parent.attr("name"))]
+	}
+}
+
+// qualify returns ref qualified by library if it is not qualified already
+func qualify(ref, library string) string {
+	if ref == "" || strings.Contains(ref, "::") {
+		return ref
+	}
+	return library + "::" + ref
+}
+
+// checkDomain checks a domain of the domain library library
+func (l *linter) checkDomain(domain *element, library string) {
+	if base := domain.attr("base_name"); base != "" && l.domains[qualify(base, library)] == nil {
+		l.issues = append(l.issues, domain.issue("domain %q has unknown base domain %q", domain.attr("name"), base))
+	}
+
+	types := make(map[string]bool)
+	topics := make(map[string]bool)
+	registeredTypes(domain, l.domains, types, topics)
+	l.checkRegistrations(domain, types)
+	l.checkQoS(domain, "")
+}
+
+// checkRegistrations checks the <register_type> and <topic> elements of a
+// domain or participant, given the types it registers
+func (l *linter) checkRegistrations(e *element, types map[string]bool) {
+	for _, registerType := range children(e, "register_type") {
+		ref := registerType.attr("type_ref")
+		if ref == "" {
+			ref = registerType.attr("name")
+		}
+		if !l.resolveType(ref, "") {
+			l.issues = append(l.issues, registerType.issue("register_type %q references unknown type %q", registerType.attr("name"), ref))
+		}
+	}
+	for _, topic := range children(e, "topic") {
+		if ref := topic.attr("register_type_ref"); ref != "" && !types[ref] {
+			l.issues = append(l.issues, topic.issue("topic %q references unregistered type %q", topic.attr("name"), ref))
+		}
+	}
+}
+
+// checkParticipant checks a participant of the participant library library
+func (l *linter) checkParticipant(participant *element, library string) {
+	if base := participant.attr("base_name"); base != "" && l.participants[qualify(base, library)] == nil {
+		l.issues = append(l.issues, participant.issue("domain_participant %q has unknown base participant %q", participant.attr("name"), base))
+	}
+
+	types := make(map[string]bool)
+	topics := make(map[string]bool)
+	if ref := l.domainRef(participant, library); ref != "" {
+		if domain := l.domains[ref]; domain != nil {
+			registeredTypes(domain, l.domains, types, topics)
+		} else {
+			l.issues = append(l.issues, participant.issue("domain_participant %q references unknown domain %q", participant.attr("name"), ref))
+		}
+	}
+	registeredTypes(participant, l.participants, types, topics)
+	l.checkRegistrations(participant, types)
+	l.checkEndpoints(participant, topics)
+	l.checkQoS(participant, "")
+}
+
+// domainRef returns the domain of a participant of the participant library
+// library, which may be inherited from its base participant
+func (l *linter) domainRef(participant *element, library string) string {
+	seen := make(map[*element]bool)
+	for p := participant; p != nil && !seen[p]; p = l.participants[qualify(p.attr("base_name"), library)] {
+		seen[p] = true
+		if ref := p.attr("domain_ref"); ref != "" {
+			return ref
+		}
+	}
+	return ""
+}
+
+// checkEndpoints checks that the data writers and readers below e reference
+// topics
+func (l *linter) checkEndpoints(e *element, topics map[string]bool) {
+	for _, child := range e.children {
+		if child.name == "data_writer" || child.name == "data_reader" {
+			if ref := child.attr("topic_ref"); !topics[ref] {
+				l.issues = append(l.issues, child.issue("%s %q references unknown topic %q", child.name, child.attr("name"), ref))
+			}
+			continue
+		}
+		l.checkEndpoints(child, topics)
+	}
+}
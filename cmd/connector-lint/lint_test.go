@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const invalidConfig = `<?xml version="1.0"?>
+<dds>
+  <qos_library name="QosLibrary">
+    <qos_profile name="Default" base_name="BuiltinQosLibExp::Generic.StrictReliable"/>
+    <qos_profile name="Derived" base_name="Missing"/>
+    <qos_profile name="Default"/>
+  </qos_library>
+  <types>
+    <module name="Display">
+      <struct name="Position">
+        <member name="x" type="int32"/>
+      </struct>
+    </module>
+    <struct name="ShapeType" baseType="Base">
+      <member name="pos" type="nonBasic" nonBasicTypeName="Display::Position"/>
+      <member name="size" type="nonBasic" nonBasicTypeName="Size"/>
+    </struct>
+  </types>
+  <domain_library name="MyDomainLibrary">
+    <domain name="MyDomain" domain_id="0">
+      <register_type name="ShapeType" type_ref="ShapeType"/>
+      <register_type name="Unknown" type_ref="UnknownType"/>
+      <topic name="Square" register_type_ref="ShapeType"/>
+      <topic name="Circle" register_type_ref="CircleType"/>
+    </domain>
+  </domain_library>
+  <domain_participant_library name="MyParticipantLibrary">
+    <domain_participant name="Zero" domain_ref="MyDomainLibrary::MyDomain">
+      <participant_qos base_name="QosLibrary::Derived"/>
+      <publisher name="MyPublisher">
+        <data_writer name="MyWriter" topic_ref="Triangle"/>
+        <data_writer name="MyWriter" topic_ref="Square">
+          <datawriter_qos base_name="QosLibrary::Unknown"/>
+        </data_writer>
+      </publisher>
+    </domain_participant>
+    <domain_participant name="One" domain_ref="MyDomainLibrary::Unknown"/>
+  </domain_participant_library>
+</dds>
+`
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func messages(issues []Issue) []string {
+	var result []string
+	for _, issue := range issues {
+		result = append(result, issue.String())
+	}
+	return result
+}
+
+func TestLint(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "config.xml", invalidConfig)
+
+	assert.Equal(t, []string{
+		path + `:5:5: qos_profile references unknown QoS profile "Missing"`,
+		path + `:6:5: duplicate qos_profile "Default", first defined at ` + path + `:4:5`,
+		path + `:14:5: struct "ShapeType" has unknown base type "Base"`,
+		path + `:16:7: member "size" has unknown type "Size"`,
+		path + `:22:7: register_type "Unknown" references unknown type "UnknownType"`,
+		path + `:24:7: topic "Circle" references unregistered type "CircleType"`,
+		path + `:31:9: data_writer "MyWriter" references unknown topic "Triangle"`,
+		path + `:32:9: duplicate data_writer "MyWriter", first defined at ` + path + `:31:9`,
+		path + `:33:11: datawriter_qos references unknown QoS profile "QosLibrary::Unknown"`,
+		path + `:37:5: domain_participant "One" references unknown domain "MyDomainLibrary::Unknown"`,
+	}, messages(lintFiles([]string{path})))
+}
+
+func TestLintTestConfigurations(t *testing.T) {
+	assert.Empty(t, lintFiles([]string{"../../test/xml/Test.xml"}))
+	assert.Empty(t, lintFiles([]string{"../../test/xml/TestConnector1.xml", "../../test/xml/TestConnector2.xml"}))
+	assert.Equal(t, []string{"../../test/xml/InvalidXml.xml:63: malformed XML: unexpected EOF"},
+		messages(lintFiles([]string{"../../test/xml/InvalidXml.xml"})))
+
+	examples, err := filepath.Glob("../../examples/*/*.xml")
+	assert.Nil(t, err)
+	for _, example := range examples {
+		assert.Empty(t, lintFiles([]string{example}), example)
+	}
+}
+
+func TestLintIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "types/types.xml", `<dds><types><struct name="ShapeType"/></types></dds>`)
+	main := writeTestFile(t, dir, "main.xml", `<dds>
+  <include file="types/types.xml"/>
+  <domain_library name="Lib">
+    <domain name="Domain"><register_type name="ShapeType" type_ref="ShapeType"/></domain>
+  </domain_library>
+</dds>`)
+	assert.Empty(t, lintFiles([]string{main}))
+
+	a := writeTestFile(t, dir, "a.xml", `<dds><include file="b.xml"/></dds>`)
+	b := writeTestFile(t, dir, "b.xml", `<dds><include file="a.xml"/></dds>`)
+	assert.Equal(t, []string{b + ":1:6: include cycle: " + a + " -> " + b + " -> " + a},
+		messages(lintFiles([]string{a})))
+
+	notDDS := writeTestFile(t, dir, "not_dds.xml", `<types/>`)
+	assert.Equal(t, []string{notDDS + ":1:1: root element is <types>, expected <dds>"},
+		messages(lintFiles([]string{notDDS})))
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	assert.Equal(t, 0, run(&out, []string{"../../test/xml/Test.xml"}))
+	assert.Empty(t, out.String())
+
+	assert.Equal(t, 1, run(&out, []string{"../../test/xml/InvalidXml.xml"}))
+	assert.True(t, strings.HasPrefix(out.String(), "../../test/xml/InvalidXml.xml:63:"))
+}
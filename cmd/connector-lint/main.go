@@ -0,0 +1,41 @@
+// RTI Connector XML Linter
+// This tool checks Connector XML configurations without starting DDS, so that
+// errors are reported with their position rather than as an invalid profile
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s file.xml...\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Checks Connector XML configurations, merged as in a single NewConnector URL,")
+		fmt.Fprintln(flag.CommandLine.Output(), "and exits non-zero if any issue is found.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if count := run(os.Stdout, flag.Args()); count > 0 {
+		fmt.Fprintf(os.Stderr, "❌ %d issue(s) found\n", count)
+		os.Exit(1)
+	}
+}
+
+// run lints the configuration made of files, prints its issues to w and
+// returns their number
+func run(w io.Writer, files []string) int {
+	issues := lintFiles(files)
+	for _, issue := range issues {
+		fmt.Fprintln(w, issue)
+	}
+	return len(issues)
+}
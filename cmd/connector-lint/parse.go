@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Issue is a problem found in a configuration, at a position of a file
+type Issue struct {
+	File    string
+	Line    int
+	Column  int // 0 when unknown
+	Message string
+}
+
+func (issue Issue) String() string {
+	if issue.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", issue.File, issue.Line, issue.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", issue.File, issue.Line, issue.Column, issue.Message)
+}
+
+// element is an XML element with the position of its start tag
+type element struct {
+	name     string
+	attrs    []xml.Attr
+	file     string
+	line     int
+	column   int
+	parent   *element
+	children []*element
+	text     string // character data, e.g. of <element>
+}
+
+// attr returns the value of an attribute of the element, or an empty string
+func (e *element) attr(name string) string {
+	for _, attr := range e.attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// position returns the position of the element, as in an Issue
+func (e *element) position() string {
+	return fmt.Sprintf("%s:%d:%d", e.file, e.line, e.column)
+}
+
+// issue returns an Issue at the position of the element
+func (e *element) issue(format string, args ...any) Issue {
+	return Issue{File: e.file, Line: e.line, Column: e.column, Message: fmt.Sprintf(format, args...)}
+}
+
+// parseFile parses an XML document whose root is <dds>, and replaces its
+// <include file="..."/> elements by the elements of the included documents,
+// resolved relative to the including one. including lists the documents
+// including this one, to detect cycles.
+func parseFile(path string, including []string) (*element, []Issue) {
+	including = append(including, path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, []Issue{{File: path, Message: err.Error()}}
+	}
+	defer f.Close()
+
+	root, err := parseElements(path, f)
+	if err != nil {
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, []Issue{{File: path, Line: syntaxErr.Line, Message: "malformed XML: " + syntaxErr.Msg}}
+		}
+		return nil, []Issue{{File: path, Line: 1, Message: err.Error()}}
+	}
+	if root.name != "dds" {
+		return nil, []Issue{root.issue("root element is <%s>, expected <dds>", root.name)}
+	}
+
+	var issues []Issue
+	var children []*element
+	for _, child := range root.children {
+		if child.name != "include" {
+			children = append(children, child)
+			continue
+		}
+
+		file := child.attr("file")
+		if file == "" {
+			issues = append(issues, child.issue("<include> without file attribute"))
+			continue
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		if slices.Contains(including, file) {
+			issues = append(issues, child.issue("include cycle: %s -> %s", strings.Join(including, " -> "), file))
+			continue
+		}
+		included, includeIssues := parseFile(file, including)
+		issues = append(issues, includeIssues...)
+		if included != nil {
+			for _, grandchild := range included.children {
+				grandchild.parent = root
+			}
+			children = append(children, included.children...)
+		}
+	}
+	root.children = children
+
+	return root, issues
+}
+
+// parseElements reads the element tree of an XML document
+func parseElements(path string, r io.Reader) (*element, error) {
+	decoder := xml.NewDecoder(r)
+
+	var root, current *element
+	for {
+		line, column := decoder.InputPos()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &element{
+				name:   t.Name.Local,
+				attrs:  t.Attr,
+				file:   path,
+				line:   line,
+				column: column,
+				parent: current,
+			}
+			if current == nil {
+				root = e
+			} else {
+				current.children = append(current.children, e)
+			}
+			current = e
+		case xml.EndElement:
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("no root element")
+	}
+	return root, nil
+}
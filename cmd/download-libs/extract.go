@@ -0,0 +1,369 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// archiveEntry is a file, directory or symbolic link of a ZIP or tar archive
+type archiveEntry struct {
+	name     string
+	mode     fs.FileMode
+	linkname string // target of a symbolic link
+	open     func() (io.ReadCloser, error)
+}
+
+// isArchiveFile tells whether a file name is a supported archive
+func isArchiveFile(name string) bool {
+	return strings.HasSuffix(name, ".zip") ||
+		strings.HasSuffix(name, ".tar.gz") ||
+		strings.HasSuffix(name, ".tgz")
+}
+
+// extractArchive extracts the libraries of an archive into the connector
+// directory. The archive is extracted into a temporary directory, then every
+// extracted lib/<platform> directory replaces the installed one, so an
+// interrupted or failed extraction never leaves a partial platform behind.
+// The rest of the connector directory, such as include/ and the platforms not
+// extracted, is left untouched.
+//
+// Only the libraries of the given platforms are extracted, or of all of them
+// if platforms is nil. The extraction fails if a platform is missing from the
+// archive with requireAll, or if all of them are missing otherwise, and if an
+// extracted platform lacks one of the required libraries.
+func extractArchive(archivePath, libDir string, platforms []string, requireAll bool, required []string) error {
+	fmt.Fprintf(out, "📂 Extracting archive...\n")
+
+	if err := os.MkdirAll(libDir, 0755); err != nil {
+		return fmt.Errorf("extracting archive: %v", err)
+	}
+	// The temporary directory is in libDir so that platform directories are
+	// moved within a single file system
+	tmpDir, err := os.MkdirTemp(libDir, ".extract-*")
+	if err != nil {
+		return fmt.Errorf("extracting archive: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	keep := keepPlatforms(platforms)
+	if strings.HasSuffix(archivePath, ".tar.gz") || strings.HasSuffix(archivePath, ".tgz") {
+		err = extractTarGz(archivePath, tmpDir, keep)
+	} else {
+		err = extractZip(archivePath, tmpDir, keep)
+	}
+	if err != nil {
+		return fmt.Errorf("extracting archive: %v", err)
+	}
+	if err := checkPlatforms(tmpDir, platforms, requireAll); err != nil {
+		return err
+	}
+
+	extracted, err := os.ReadDir(filepath.Join(tmpDir, "lib"))
+	if err != nil || len(extracted) == 0 {
+		return fmt.Errorf("archive has no libraries")
+	}
+	if err := checkRequired(tmpDir, extracted, required); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(libDir, "lib"), 0755); err != nil {
+		return fmt.Errorf("installing libraries: %v", err)
+	}
+	for _, platform := range extracted {
+		src := filepath.Join(tmpDir, "lib", platform.Name())
+		if err := replaceDir(src, filepath.Join(libDir, "lib", platform.Name())); err != nil {
+			return fmt.Errorf("installing libraries: %v", err)
+		}
+	}
+
+	fmt.Fprintf(out, "✅ Libraries installed to: %s\n", libDir)
+	return nil
+}
+
+// replaceDir moves src to dst, replacing any existing dst. The previous dst is
+// only removed once src is in place, and is restored if the move fails.
+func replaceDir(src, dst string) error {
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		return os.Rename(src, dst)
+	}
+
+	old := src + ".old"
+	if err := os.Rename(dst, old); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		os.Rename(old, dst)
+		return err
+	}
+
+	return os.RemoveAll(old)
+}
+
+// keepPlatforms returns whether an entry belongs to the libraries of one of
+// the platforms, below lib/<platform>/. Other entries are never extracted. A
+// nil list of platforms keeps the libraries of every platform.
+func keepPlatforms(platforms []string) func(name string) bool {
+	return func(name string) bool {
+		parts := strings.SplitN(strings.TrimPrefix(name, "./"), "/", 3)
+		if len(parts) < 3 || parts[0] != "lib" || parts[1] == "" {
+			return false
+		}
+		return platforms == nil || slices.Contains(platforms, parts[1])
+	}
+}
+
+// checkName rejects entry names that are absolute or escape the extraction
+// directory, whether the entry is extracted or not
+func checkName(name string) error {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("unsafe path %q in archive", name)
+	}
+	return nil
+}
+
+// checkPlatforms checks that the libraries of the platforms were extracted
+func checkPlatforms(root string, platforms []string, requireAll bool) error {
+	var missing []string
+	for _, platform := range platforms {
+		if _, err := os.Stat(filepath.Join(root, "lib", platform)); err != nil {
+			missing = append(missing, platform)
+		}
+	}
+
+	if len(missing) > 0 && (requireAll || len(missing) == len(platforms)) {
+		return fmt.Errorf("archive has no libraries for %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkRequired checks that every extracted platform has the required
+// libraries, such as the static archives that the static build links
+func checkRequired(root string, platforms []os.DirEntry, required []string) error {
+	for _, platform := range platforms {
+		for _, name := range required {
+			if _, err := os.Stat(filepath.Join(root, "lib", platform.Name(), name)); err != nil {
+				return fmt.Errorf("archive has no %s for %s", name, platform.Name())
+			}
+		}
+	}
+	return nil
+}
+
+func extractZip(src, root string, keep func(name string) bool) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	entries := make([]archiveEntry, 0, len(r.File))
+	for _, f := range r.File {
+		if err := checkName(f.Name); err != nil {
+			return err
+		}
+		if !keep(f.Name) {
+			continue
+		}
+		entry := archiveEntry{name: f.Name, mode: f.Mode(), open: f.Open}
+		if entry.mode&fs.ModeSymlink != 0 {
+			// ZIP archives store the target of a link as its content
+			target, err := readAll(f.Open)
+			if err != nil {
+				return err
+			}
+			entry.linkname = string(target)
+		}
+		entries = append(entries, entry)
+	}
+
+	return extractEntries(entries, root)
+}
+
+func extractTarGz(src, root string, keep func(name string) bool) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	// A tar stream can only be read once, so entries are extracted as they
+	// are read, and links once every file is in place
+	var links []archiveEntry
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkName(header.Name); err != nil {
+			return err
+		}
+		if !keep(header.Name) {
+			continue
+		}
+
+		entry := archiveEntry{
+			name: header.Name,
+			mode: header.FileInfo().Mode(),
+			open: func() (io.ReadCloser, error) { return io.NopCloser(tr), nil },
+		}
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeDir:
+			if err := extractEntry(entry, root); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			entry.linkname = header.Linkname
+			links = append(links, entry)
+		case tar.TypeLink:
+			// Hard links are installed as symbolic links to the same file
+			entry.mode = fs.ModeSymlink
+			entry.linkname = filepath.Base(header.Linkname)
+			if filepath.ToSlash(filepath.Dir(header.Linkname)) != filepath.ToSlash(filepath.Dir(header.Name)) {
+				return fmt.Errorf("unsupported hard link %q -> %q in archive", header.Name, header.Linkname)
+			}
+			links = append(links, entry)
+		case tar.TypeXGlobalHeader:
+		default:
+			return fmt.Errorf("unsupported entry %q in archive", header.Name)
+		}
+	}
+
+	return extractEntries(links, root)
+}
+
+// extractEntries extracts files and directories, then symbolic links, so that
+// no file is ever written through a link
+func extractEntries(entries []archiveEntry, root string) error {
+	for _, entry := range entries {
+		if entry.mode&fs.ModeSymlink == 0 {
+			if err := extractEntry(entry, root); err != nil {
+				return err
+			}
+		}
+	}
+	for _, entry := range entries {
+		if entry.mode&fs.ModeSymlink != 0 {
+			if err := extractEntry(entry, root); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// extractEntry extracts a single entry below root. Entries escaping root are
+// rejected, and permissions are reduced to 0755 or 0644.
+func extractEntry(entry archiveEntry, root string) error {
+	// The archive contains lib/* which is extracted to rticonnextdds-connector/lib/*
+	if err := checkName(entry.name); err != nil {
+		return err
+	}
+	path := filepath.Join(root, filepath.FromSlash(entry.name))
+
+	switch {
+	case entry.mode.IsDir():
+		return os.MkdirAll(path, 0755)
+
+	case entry.mode&fs.ModeSymlink != 0:
+		return extractSymlink(entry, path)
+
+	case entry.mode.IsRegular():
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return extractFile(entry, path)
+
+	default:
+		return fmt.Errorf("unsupported entry %q in archive", entry.name)
+	}
+}
+
+func extractFile(entry archiveEntry, path string) error {
+	perm := fs.FileMode(0644)
+	if entry.mode&0111 != 0 {
+		perm = 0755
+	}
+
+	rc, err := entry.open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// O_EXCL refuses entries appearing twice in the archive
+	outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(outFile, rc)
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// extractSymlink creates a symbolic link. Only links to a file of the same
+// directory are accepted, as used for versioned shared libraries
+// (libfoo.so -> libfoo.so.1), so a link can never point outside root.
+func extractSymlink(entry archiveEntry, path string) error {
+	target := entry.linkname
+	if target == "" || target == "." || target == ".." || strings.ContainsAny(target, `/\`) {
+		return fmt.Errorf("unsafe link %q -> %q in archive", entry.name, target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	err := os.Symlink(target, path)
+	if err != nil {
+		// Creating links may require privileges (e.g., on Windows): copy the
+		// target instead
+		return copyFile(filepath.Join(filepath.Dir(path), target), path)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot copy %s: not a regular file", src)
+	}
+
+	return extractFile(archiveEntry{
+		name: dst,
+		mode: info.Mode(),
+		open: func() (io.ReadCloser, error) { return os.Open(src) },
+	}, dst)
+}
+
+func readAll(open func() (io.ReadCloser, error)) ([]byte, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
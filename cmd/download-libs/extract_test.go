@@ -0,0 +1,232 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testEntry describes an archive entry: a file with content, or a link to
+// target
+type testEntry struct {
+	name    string
+	content string
+	target  string
+	mode    fs.FileMode
+}
+
+func writeTestZip(t *testing.T, entries []testEntry) string {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		content := e.content
+		if e.target != "" {
+			header.SetMode(fs.ModeSymlink | 0777)
+			content = e.target
+		} else {
+			header.SetMode(e.mode)
+		}
+		f, err := w.CreateHeader(header)
+		assert.Nil(t, err)
+		_, err = f.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Close())
+
+	path := filepath.Join(t.TempDir(), "connector.zip")
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+	return path
+}
+
+func writeTestTarGz(t *testing.T, entries []testEntry) string {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: int64(e.mode.Perm()), Size: int64(len(e.content))}
+		switch {
+		case e.target != "":
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = e.target
+			header.Size = 0
+		case e.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+		default:
+			header.Typeflag = tar.TypeReg
+		}
+		assert.Nil(t, w.WriteHeader(header))
+		if header.Typeflag == tar.TypeReg {
+			_, err := w.Write([]byte(e.content))
+			assert.Nil(t, err)
+		}
+	}
+	assert.Nil(t, w.Close())
+	assert.Nil(t, gz.Close())
+
+	path := filepath.Join(t.TempDir(), "connector.tar.gz")
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+	return path
+}
+
+var testLibraryEntries = []testEntry{
+	{name: "lib/", mode: fs.ModeDir | 0777},
+	{name: "lib/linux-x64/librtiddsconnector.so", target: "librtiddsconnector.so.7"},
+	{name: "lib/linux-x64/librtiddsconnector.so.7", content: "library", mode: 0777},
+	{name: "include/rticonnextdds-connector.h", content: "header", mode: 0666},
+}
+
+func TestExtractArchives(t *testing.T) {
+	for name, write := range map[string]func(*testing.T, []testEntry) string{
+		"zip":    writeTestZip,
+		"tar.gz": writeTestTarGz,
+	} {
+		t.Run(name, func(t *testing.T) {
+			libDir := filepath.Join(t.TempDir(), "rticonnextdds-connector")
+			assert.Nil(t, extractArchive(write(t, testLibraryEntries), libDir, nil, false, nil))
+
+			content, err := os.ReadFile(filepath.Join(libDir, "lib", "linux-x64", "librtiddsconnector.so"))
+			assert.Nil(t, err)
+			assert.Equal(t, "library", string(content))
+
+			if runtime.GOOS != "windows" {
+				target, err := os.Readlink(filepath.Join(libDir, "lib", "linux-x64", "librtiddsconnector.so"))
+				assert.Nil(t, err)
+				assert.Equal(t, "librtiddsconnector.so.7", target)
+
+				// Modes are sanitized
+				info, err := os.Stat(filepath.Join(libDir, "lib", "linux-x64", "librtiddsconnector.so.7"))
+				assert.Nil(t, err)
+				assert.Equal(t, fs.FileMode(0755), info.Mode().Perm())
+			}
+
+			// Only the libraries are extracted, and no temporary directory
+			// is left behind
+			entries, err := os.ReadDir(libDir)
+			assert.Nil(t, err)
+			assert.Len(t, entries, 1)
+			assert.Equal(t, "lib", entries[0].Name())
+		})
+	}
+}
+
+// Tests that extracting an archive only replaces the directories of the
+// platforms it installs, and keeps the headers and other platforms
+func TestExtractKeepsConnectorDirectory(t *testing.T) {
+	libDir := filepath.Join(t.TempDir(), "rticonnextdds-connector")
+	header := filepath.Join(libDir, "include", "rticonnextdds-connector.h")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(header), 0755))
+	assert.Nil(t, os.WriteFile(header, []byte("local header"), 0644))
+	assert.Nil(t, os.MkdirAll(filepath.Join(libDir, "lib", "osx-x64"), 0755))
+	stale := filepath.Join(libDir, "lib", "linux-x64", "libstale.so")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(stale), 0755))
+	assert.Nil(t, os.WriteFile(stale, []byte("stale"), 0644))
+
+	assert.Nil(t, extractArchive(writeTestZip(t, testLibraryEntries), libDir, nil, false, nil))
+
+	content, err := os.ReadFile(header)
+	assert.Nil(t, err)
+	assert.Equal(t, "local header", string(content))
+	assert.Equal(t, []string{"linux-x64", "osx-x64"}, installedPlatforms(libDir))
+	// The installed platform is replaced as a whole
+	_, err = os.Stat(stale)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// Tests that unsafe entries fail the extraction and leave a previous
+// installation untouched
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	unsafe := map[string][]testEntry{
+		"parent path":    {{name: "../evil.so", content: "evil"}},
+		"absolute path":  {{name: "/tmp/evil.so", content: "evil"}},
+		"absolute link":  {{name: "lib/linux-x64/evil.so", target: "/etc/passwd"}},
+		"parent link":    {{name: "lib/linux-x64/evil.so", target: "../../evil.so"}},
+		"duplicate file": {{name: "lib/linux-x64/a.so", content: "a"}, {name: "lib/linux-x64/a.so", content: "b"}},
+	}
+
+	for name, entries := range unsafe {
+		for format, write := range map[string]func(*testing.T, []testEntry) string{
+			"zip":    writeTestZip,
+			"tar.gz": writeTestTarGz,
+		} {
+			t.Run(name+" "+format, func(t *testing.T) {
+				libDir := filepath.Join(t.TempDir(), "rticonnextdds-connector")
+				assert.Nil(t, extractArchive(write(t, testLibraryEntries), libDir, nil, false, nil))
+
+				entries := append(append([]testEntry{}, testLibraryEntries...), entries...)
+				err := extractArchive(write(t, entries), libDir, nil, false, nil)
+				assert.NotNil(t, err)
+
+				content, err := os.ReadFile(filepath.Join(libDir, "lib", "linux-x64", "librtiddsconnector.so.7"))
+				assert.Nil(t, err)
+				assert.Equal(t, "library", string(content))
+				_, err = os.Lstat(filepath.Join(libDir, "lib", "linux-x64", "evil.so"))
+				assert.True(t, os.IsNotExist(err))
+
+				dirEntries, err := os.ReadDir(libDir)
+				assert.Nil(t, err)
+				assert.Len(t, dirEntries, 1)
+			})
+		}
+	}
+}
+
+func TestInstallLibrariesTo(t *testing.T) {
+	preferred, _ := getPlatformWithFallback()
+	entries := []testEntry{
+		{name: "lib/" + preferred + "/librtiddsconnector.so", target: "librtiddsconnector.so.7"},
+		{name: "lib/" + preferred + "/librtiddsconnector.so.7", content: "library", mode: 0755},
+		{name: "lib/" + preferred + "/README.txt", content: "not a library", mode: 0644},
+	}
+	dest := t.TempDir()
+	assert.Nil(t, extractArchive(writeTestZip(t, entries), filepath.Join(dest, "rticonnextdds-connector"), nil, false, nil))
+
+	bin := filepath.Join(t.TempDir(), "bin")
+	assert.Nil(t, installLibrariesTo(dest, bin))
+	// Installing again replaces the previous copy
+	assert.Nil(t, installLibrariesTo(dest, bin))
+
+	content, err := os.ReadFile(filepath.Join(bin, "librtiddsconnector.so"))
+	assert.Nil(t, err)
+	assert.Equal(t, "library", string(content))
+	_, err = os.Stat(filepath.Join(bin, "librtiddsconnector.so.7"))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(bin, "README.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NotNil(t, installLibrariesTo(t.TempDir(), bin))
+}
+
+func TestExtractPlatforms(t *testing.T) {
+	var entries []testEntry
+	for _, platform := range []string{"linux-x64", "linux-arm64", "osx-arm64"} {
+		entries = append(entries,
+			testEntry{name: "lib/" + platform + "/", mode: fs.ModeDir | 0755},
+			testEntry{name: "lib/" + platform + "/librtiddsconnector.so", content: platform, mode: 0755})
+	}
+	entries = append(entries, testEntry{name: "include/rticonnextdds-connector.h", content: "header", mode: 0644})
+	archive := writeTestTarGz(t, entries)
+
+	libDir := filepath.Join(t.TempDir(), "rticonnextdds-connector")
+	assert.Nil(t, extractArchive(archive, libDir, []string{"linux-arm64", "linux-x64"}, true, nil))
+	assert.Equal(t, []string{"linux-arm64", "linux-x64"}, installedPlatforms(libDir))
+
+	assert.Nil(t, extractArchive(archive, libDir, nil, false, nil))
+	assert.Equal(t, []string{"linux-arm64", "linux-x64", "osx-arm64"}, installedPlatforms(libDir))
+
+	// A missing platform fails when required, and only if no platform is
+	// available otherwise
+	assert.NotNil(t, extractArchive(archive, libDir, []string{"linux-x64", "win-x64"}, true, nil))
+	assert.Equal(t, []string{"linux-arm64", "linux-x64", "osx-arm64"}, installedPlatforms(libDir))
+	assert.Nil(t, extractArchive(archive, libDir, []string{"osx-arm64", "osx-x64"}, false, nil))
+	assert.Equal(t, []string{"linux-arm64", "linux-x64", "osx-arm64"}, installedPlatforms(libDir))
+	assert.NotNil(t, extractArchive(archive, libDir, []string{"win-x64"}, false, nil))
+}
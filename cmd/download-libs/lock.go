@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// lockFileName is the file pinning the installed libraries. It is meant to be
+// committed alongside go.mod so that every checkout installs the same archive.
+const lockFileName = "connector-libs.lock"
+
+// staticLockFileName is the lock file of the static libraries
+const staticLockFileName = "connector-libs-static.lock"
+
+// LockFile records the archive the libraries were installed from, the
+// platform they were installed for, and the libraries themselves
+type LockFile struct {
+	Version  string `json:"version"`
+	Platform string `json:"platform,omitempty"`
+	Archive  string `json:"archive"`
+	SHA256   string `json:"sha256"`
+	// Libraries maps every installed library, relative to the lib directory
+	// (e.g., "linux-x64/librtiddsconnector.so"), to its SHA-256
+	Libraries map[string]string `json:"libraries,omitempty"`
+}
+
+// defaultLockPath returns the lock file next to the go.mod of the module
+// containing dest, or in dest itself when it is not inside a module. The
+// static libraries have their own lock file.
+func defaultLockPath(dest string, static bool) string {
+	lockFileName := lockFileName
+	if static {
+		lockFileName = staticLockFileName
+	}
+
+	dir, err := filepath.Abs(dest)
+	if err != nil {
+		return filepath.Join(dest, lockFileName)
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return filepath.Join(dir, lockFileName)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return filepath.Join(dest, lockFileName)
+		}
+		dir = parent
+	}
+}
+
+// readLockFile returns the lock file at path, or nil if it does not exist
+func readLockFile(path string) (*LockFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lock LockFile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+
+	return &lock, nil
+}
+
+// lockedVersion returns the version pinned by the lock file at path, or an
+// empty string if there is none
+func lockedVersion(path string) string {
+	lock, err := readLockFile(path)
+	if err != nil || lock == nil {
+		return ""
+	}
+	if lock.Version != "" {
+		fmt.Fprintf(out, "📌 Using version %s from %s\n", lock.Version, path)
+	}
+	return lock.Version
+}
+
+func writeLockFile(path string, lock LockFile) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// pinnedChecksum returns the checksum an archive must have according to the
+// command line or the lock file, and where it comes from
+func pinnedChecksum(opts installOptions, lock *LockFile, version, archiveName string) (string, string) {
+	if opts.checksum != "" {
+		return opts.checksum, "-sha256"
+	}
+	if lock != nil && lock.SHA256 != "" {
+		if (version != "" && lock.Version == version) || (archiveName != "" && lock.Archive == archiveName) {
+			return lock.SHA256, lockFileName
+		}
+	}
+	return "", ""
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyChecksum checks the SHA-256 of the archive archiveName, stored at
+// archivePath, against the expected one and returns the actual checksum.
+// Without an expected checksum the archive is refused, unless unpinned
+// archives are explicitly allowed, in which case its checksum gets pinned.
+func verifyChecksum(archivePath, archiveName, expected, source string, allowUnpinned bool) (string, error) {
+	actual, err := fileSHA256(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("computing checksum: %v", err)
+	}
+
+	if expected == "" {
+		if !allowUnpinned {
+			return "", fmt.Errorf("no SHA-256 checksum available for %s (use -sha256, a %s, or -allow-unpinned to trust it)", archiveName, lockFileName)
+		}
+		fmt.Fprintf(out, "⚠️  No published checksum for %s, pinning SHA-256 %s\n", archiveName, actual)
+		return actual, nil
+	}
+
+	if !strings.EqualFold(actual, expected) {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s (from %s), got %s",
+			archiveName, strings.ToLower(expected), source, actual)
+	}
+
+	fmt.Fprintf(out, "🔒 Verified SHA-256 (from %s): %s\n", source, actual)
+	return actual, nil
+}
+
+// findChecksumAsset returns the release asset holding the checksum of an
+// archive: either "<archive>.sha256" or a SHA256SUMS style list
+func findChecksumAsset(release Release, archiveName string) (Asset, bool) {
+	for _, asset := range release.Assets {
+		if asset.Name == archiveName+".sha256" {
+			return asset, true
+		}
+	}
+	for _, asset := range release.Assets {
+		switch strings.ToLower(asset.Name) {
+		case "sha256sums", "sha256sums.txt", "checksums.txt":
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// fetchReleaseChecksum downloads the published checksum of an archive, or
+// returns an empty string if the release does not publish one
+func fetchReleaseChecksum(release Release, archiveName string) (string, error) {
+	asset, ok := findChecksumAsset(release, archiveName)
+	if !ok {
+		return "", nil
+	}
+
+	resp, err := http.Get(asset.BrowserDownloadURL)
+	if err != nil {
+		return "", fmt.Errorf("downloading checksum: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading checksum: %s", resp.Status)
+	}
+
+	return parseChecksum(resp.Body, archiveName)
+}
+
+// parseChecksum reads a checksum file in the format of sha256sum: either a
+// single hash, or lines of "<hash>  <file name>"
+func parseChecksum(r io.Reader, archiveName string) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 1:
+			return checkHexSHA256(fields[0])
+		case len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == archiveName:
+			return checkHexSHA256(fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no checksum for %s in checksum file", archiveName)
+}
+
+func checkHexSHA256(s string) (string, error) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 checksum %q", s)
+	}
+	return strings.ToLower(s), nil
+}
+
+// hashLibraries returns the SHA-256 of every library below libRoot, keyed by
+// its slash separated path relative to libRoot
+func hashLibraries(libRoot string) (map[string]string, error) {
+	libraries := make(map[string]string)
+	err := filepath.WalkDir(libRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !isLibraryFile(entry.Name()) {
+			return err
+		}
+
+		rel, err := filepath.Rel(libRoot, path)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		libraries[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return libraries, nil
+}
+
+// checkLibraries compares the libraries installed in libDir with the ones
+// recorded in the lock file, and returns the differences, with an error if
+// there are any
+func checkLibraries(libDir, lockPath string) ([]string, error) {
+	lock, err := readLockFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return nil, fmt.Errorf("no lock file at %s", lockPath)
+	}
+	if len(lock.Libraries) == 0 {
+		return nil, fmt.Errorf("%s records no libraries, reinstall with -force to record them", lockPath)
+	}
+
+	installed, err := hashLibraries(filepath.Join(libDir, "lib"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("computing library checksums: %v", err)
+	}
+
+	fmt.Fprintf(out, "🔍 Checking %s against %s (%s, %s)\n", libDir, lockPath, lock.Version, lock.Platform)
+
+	var drift []string
+	for name, sum := range lock.Libraries {
+		switch actual, ok := installed[name]; {
+		case !ok:
+			drift = append(drift, "missing: "+name)
+		case !strings.EqualFold(actual, sum):
+			drift = append(drift, "modified: "+name)
+		}
+	}
+	for name := range installed {
+		if _, ok := lock.Libraries[name]; !ok {
+			drift = append(drift, "not in lock file: "+name)
+		}
+	}
+
+	if len(drift) > 0 {
+		sort.Strings(drift)
+		for _, d := range drift {
+			fmt.Fprintf(out, "  %s\n", d)
+		}
+		return drift, fmt.Errorf("installed libraries do not match %s", lockPath)
+	}
+
+	fmt.Fprintf(out, "✅ Installed libraries match %s\n", lockPath)
+	return nil, nil
+}
@@ -3,7 +3,6 @@
 package main
 
 import (
-	"archive/zip"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -12,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 )
 
@@ -19,60 +19,204 @@ const (
 	repoOwner = "rticommunity"
 	repoName  = "rticonnextdds-connector"
 	baseURL   = "https://api.github.com/repos/" + repoOwner + "/" + repoName
+
+	// Directories, relative to -dest, of the shared and static libraries
+	connectorDirName       = "rticonnextdds-connector"
+	staticConnectorDirName = "rticonnextdds-connector-static"
 )
 
+// staticLibraries are the archives that the static build links, which every
+// platform of a static archive must ship
+var staticLibraries = []string{"librtiddsconnectorz.a", "libnddscz.a", "libnddscorez.a"}
+
+// apiURL is the GitHub releases API of the connector repository, or a mirror
+// of it selected with -mirror
+var apiURL = baseURL
+
 type Release struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
+	TagName string  `json:"tag_name"`
+	Name    string  `json:"name"`
+	Assets  []Asset `json:"assets"`
+}
+
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// installOptions control how an archive is obtained, verified and installed
+type installOptions struct {
+	dest          string
+	cacheDir      string
+	lockPath      string
+	checksum      string // expected SHA-256 given on the command line
+	force         bool
+	allowUnpinned bool // trust archives without a published or pinned checksum
+	static        bool // install the static libraries
+	// platforms whose libraries are installed, all of them if nil
+	platforms []string
+	// requirePlatforms fails the installation if a platform is missing from
+	// the archive, rather than when none of them is present
+	requirePlatforms bool
+}
+
+// libDir returns the directory the libraries are installed to
+func (opts installOptions) libDir() string {
+	if opts.static {
+		return filepath.Join(opts.dest, staticConnectorDirName)
+	}
+	return filepath.Join(opts.dest, connectorDirName)
+}
+
+// requiredLibraries returns the libraries that every installed platform must
+// have
+func (opts installOptions) requiredLibraries() []string {
+	if opts.static {
+		return staticLibraries
+	}
+	return nil
 }
 
 func main() {
 	var (
-		version     = flag.String("version", "", "Specific version to download (e.g., v1.3.1)")
-		list        = flag.Bool("list", false, "List available versions")
-		current     = flag.Bool("current", false, "Show current installation info")
-		force       = flag.Bool("force", false, "Force download even if libraries exist")
-		destination = flag.String("dest", ".", "Destination directory for libraries")
+		version       = flag.String("version", "", "Specific version to download (e.g., v1.3.1, or latest to ignore the lock file)")
+		list          = flag.Bool("list", false, "List available versions")
+		current       = flag.Bool("current", false, "Show current installation info")
+		force         = flag.Bool("force", false, "Force download even if libraries exist")
+		destination   = flag.String("dest", ".", "Destination directory for libraries")
+		fromArchive   = flag.String("from-archive", "", "Install from a local .zip or .tar.gz archive instead of downloading")
+		mirror        = flag.String("mirror", "", "Base URL of a mirror of the GitHub releases API (e.g., http://artifacts.local/connector)")
+		cacheDir      = flag.String("cache-dir", defaultCacheDir(), "Directory caching downloaded archives by version and archive name")
+		noCache       = flag.Bool("no-cache", false, "Do not read or write the archive cache")
+		checksum      = flag.String("sha256", "", "Expected SHA-256 checksum of the archive")
+		lockPath      = flag.String("lock", "", "Lock file pinning the archive checksum (default: "+lockFileName+" next to go.mod)")
+		allowUnpinned = flag.Bool("allow-unpinned", false, "Trust and pin archives without a published or pinned checksum")
+		check         = flag.Bool("check", false, "Check that the installed libraries match the lock file (exits non-zero on drift)")
+		installTo     = flag.String("install-to", "", "Also copy the libraries of this platform to a directory, e.g. next to the built binary")
+		static        = flag.Bool("static", false, "Install the static libraries, for builds with -tags static, to "+staticConnectorDirName)
+		jsonOutput    = flag.Bool("json", false, "Print the result as JSON on stdout, and progress on stderr")
+		platforms     = flag.String("platform", "", "Comma separated platforms to install (e.g., linux-x64,linux-arm64; default: this host)")
+		all           = flag.Bool("all", false, "Install the libraries of every platform in the release")
 	)
 	flag.Parse()
 
-	if *list {
-		listVersions()
-		return
+	if *jsonOutput {
+		out = os.Stderr
 	}
 
-	if *current {
-		showCurrent(*destination)
-		return
+	if *mirror != "" {
+		apiURL = strings.TrimSuffix(*mirror, "/")
+	}
+	if *noCache {
+		*cacheDir = ""
+	}
+	if *lockPath == "" {
+		*lockPath = defaultLockPath(*destination, *static)
 	}
 
-	targetVersion := *version
-	if targetVersion == "" {
-		var err error
-		targetVersion, err = getLatestVersion()
-		if err != nil {
-			fmt.Printf("Error getting latest version: %v\n", err)
-			os.Exit(1)
+	opts := installOptions{
+		dest:          *destination,
+		cacheDir:      *cacheDir,
+		lockPath:      *lockPath,
+		checksum:      *checksum,
+		force:         *force,
+		allowUnpinned: *allowUnpinned,
+		static:        *static,
+	}
+
+	switch {
+	case *all:
+	case *platforms != "":
+		for _, platform := range strings.Split(*platforms, ",") {
+			platform = strings.TrimSpace(platform)
+			if !slices.Contains(knownPlatforms, platform) {
+				fmt.Fprintf(out, "❌ Error: unknown platform %q (known: %s)\n", platform, strings.Join(knownPlatforms, ", "))
+				os.Exit(1)
+			}
+			opts.platforms = append(opts.platforms, platform)
 		}
-		fmt.Printf("Latest version: %s\n", targetVersion)
-		if targetVersion == "" {
-			fmt.Printf("Error: Latest version is empty\n")
-			os.Exit(1)
+		opts.requirePlatforms = true
+	default:
+		preferred, fallback := getPlatformWithFallback()
+		opts.platforms = []string{preferred}
+		if fallback != "" {
+			opts.platforms = append(opts.platforms, fallback)
 		}
 	}
 
-	err := downloadLibraries(targetVersion, *destination, *force)
+	var result Result
+	var err error
+	switch {
+	case *list:
+		result, err = listVersions()
+	case *current:
+		result, err = showCurrent(opts.libDir())
+	case *check:
+		result.Drift, err = checkLibraries(opts.libDir(), *lockPath)
+	default:
+		result, err = install(*fromArchive, *version, *installTo, opts)
+	}
+
+	if *jsonOutput {
+		if err != nil {
+			result.Error = err.Error()
+		}
+		printJSON(result)
+	} else if err != nil {
+		fmt.Fprintf(out, "❌ Error: %v\n", err)
+	}
 	if err != nil {
-		fmt.Printf("Error downloading libraries: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	fmt.Println("\n✅ Libraries downloaded successfully!")
-	showSetupInstructions(*destination)
+// install downloads the libraries, or installs them from archivePath, and
+// describes the installation
+func install(archivePath, version, installTo string, opts installOptions) (Result, error) {
+	if archivePath != "" {
+		if err := installArchive(archivePath, version, opts); err != nil {
+			return Result{}, fmt.Errorf("installing libraries: %v", err)
+		}
+		fmt.Fprintln(out, "\n✅ Libraries installed successfully!")
+	} else {
+		// Without -version, install the version pinned by the lock file
+		if version == "" {
+			version = lockedVersion(opts.lockPath)
+		} else if version == "latest" {
+			version = ""
+		}
+		if version == "" {
+			var err error
+			version, err = getLatestVersion()
+			if err != nil {
+				return Result{}, fmt.Errorf("getting latest version: %v", err)
+			}
+			fmt.Fprintf(out, "Latest version: %s\n", version)
+		}
+
+		if err := downloadLibraries(version, opts); err != nil {
+			return Result{}, fmt.Errorf("downloading libraries: %v", err)
+		}
+		fmt.Fprintln(out, "\n✅ Libraries downloaded successfully!")
+	}
+
+	if err := finishInstall(opts, installTo); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Version: version, InstalledTo: installTo}
+	if lock, err := readLockFile(opts.lockPath); err == nil && lock != nil {
+		result.Version = lock.Version
+		result.Archive = lock.Archive
+		result.SHA256 = lock.SHA256
+	}
+	inst, err := currentInstallation(opts.libDir())
+	if err != nil {
+		return result, err
+	}
+	result.Installation = &inst
+
+	return result, nil
 }
 
 func detectPlatform() string {
@@ -84,7 +228,7 @@ func detectPlatform() string {
 		case "arm64":
 			return "linux-arm64"
 		default:
-			fmt.Printf("Unsupported Linux architecture: %s\n", runtime.GOARCH)
+			fmt.Fprintf(out, "Unsupported Linux architecture: %s\n", runtime.GOARCH)
 			os.Exit(1)
 		}
 	case "darwin":
@@ -94,18 +238,21 @@ func detectPlatform() string {
 		case "arm64":
 			return "osx-arm64"
 		default:
-			fmt.Printf("Unsupported macOS architecture: %s\n", runtime.GOARCH)
+			fmt.Fprintf(out, "Unsupported macOS architecture: %s\n", runtime.GOARCH)
 			os.Exit(1)
 		}
 	case "windows":
 		return "win-x64"
 	default:
-		fmt.Printf("Unsupported operating system: %s\n", runtime.GOOS)
+		fmt.Fprintf(out, "Unsupported operating system: %s\n", runtime.GOOS)
 		os.Exit(1)
 	}
 	return ""
 }
 
+// knownPlatforms are the platform directories of the connector releases
+var knownPlatforms = []string{"linux-x64", "linux-arm64", "linux-arm", "osx-x64", "osx-arm64", "win-x64"}
+
 // getPlatformWithFallback returns the preferred platform and a fallback if needed
 func getPlatformWithFallback() (string, string) {
 	preferred := detectPlatform()
@@ -120,31 +267,36 @@ func getPlatformWithFallback() (string, string) {
 	return preferred, fallback
 }
 
-func listVersions() {
-	fmt.Println("📋 Available Versions:")
-	resp, err := http.Get(baseURL + "/releases")
+func listVersions() (Result, error) {
+	resp, err := http.Get(apiURL + "/releases")
 	if err != nil {
-		fmt.Printf("Error fetching versions: %v\n", err)
-		return
+		return Result{}, fmt.Errorf("fetching versions: %v", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("fetching versions: %s", resp.Status)
+	}
+
 	var releases []Release
 	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		fmt.Printf("Error parsing releases: %v\n", err)
-		return
+		return Result{}, fmt.Errorf("parsing releases: %v", err)
 	}
 
+	var result Result
+	fmt.Fprintln(out, "📋 Available Versions:")
 	for i, release := range releases {
-		if i >= 10 { // Show latest 10 versions
-			break
+		result.Versions = append(result.Versions, release.TagName)
+		if i < 10 { // Show latest 10 versions
+			fmt.Fprintf(out, "  %s\n", release.TagName)
 		}
-		fmt.Printf("  %s\n", release.TagName)
 	}
+
+	return result, nil
 }
 
 func getLatestVersion() (string, error) {
-	resp, err := http.Get(baseURL + "/releases/latest")
+	resp, err := http.Get(apiURL + "/releases/latest")
 	if err != nil {
 		return "", err
 	}
@@ -166,238 +318,528 @@ func getLatestVersion() (string, error) {
 	return release.TagName, nil
 }
 
-func getDownloadURL(version string) (string, string, error) {
-	releaseURL := fmt.Sprintf("%s/releases/tags/%s", baseURL, version)
+func getRelease(version string) (Release, error) {
+	var release Release
+
+	releaseURL := fmt.Sprintf("%s/releases/tags/%s", apiURL, version)
 	resp, err := http.Get(releaseURL)
 	if err != nil {
-		return "", "", err
+		return release, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("release %s not found", version)
+		return release, fmt.Errorf("release %s not found", version)
 	}
 
-	var release Release
 	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", "", err
+		return release, err
 	}
 
-	// Find the ZIP asset
-	for _, asset := range release.Assets {
-		if strings.HasSuffix(asset.Name, ".zip") {
-			return asset.BrowserDownloadURL, asset.Name, nil
+	return release, nil
+}
+
+// findArchiveAsset returns the archive of the shared libraries of a release,
+// or of the static libraries, whose asset name contains "static"
+func findArchiveAsset(release Release, static bool) (Asset, error) {
+	// Prefer the ZIP asset, then a tarball
+	for _, suffix := range []string{".zip", ".tar.gz", ".tgz"} {
+		for _, asset := range release.Assets {
+			if strings.HasSuffix(asset.Name, suffix) && strings.Contains(strings.ToLower(asset.Name), "static") == static {
+				return asset, nil
+			}
 		}
 	}
 
-	return "", "", fmt.Errorf("no ZIP asset found in release %s", version)
+	if static {
+		return Asset{}, fmt.Errorf("release %s publishes no static libraries", release.TagName)
+	}
+	return Asset{}, fmt.Errorf("no ZIP or tar.gz asset found in release %s", release.TagName)
 }
 
-func showCurrent(dest string) {
-	preferred, fallback := getPlatformWithFallback()
-	libPath := filepath.Join(dest, "rticonnextdds-connector", "lib", preferred)
+func showCurrent(libDir string) (Result, error) {
+	inst, err := currentInstallation(libDir)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Installation: &inst}
 
-	fmt.Println("📋 Current Installation:")
-	fmt.Printf("  Platform: %s", preferred)
-	if fallback != "" {
-		fmt.Printf(" (fallback: %s)", fallback)
+	fmt.Fprintln(out, "📋 Current Installation:")
+	fmt.Fprintf(out, "  Platform: %s", inst.Platform)
+	if inst.Fallback != "" {
+		fmt.Fprintf(out, " (fallback: %s)", inst.Fallback)
 	}
-	fmt.Printf("\n")
-	fmt.Printf("  Library path: %s\n", libPath)
+	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "  Library path: %s\n", inst.LibraryPath)
 
-	// Check preferred platform first
-	if _, err := os.Stat(libPath); os.IsNotExist(err) {
-		// Try fallback if preferred doesn't exist
-		if fallback != "" {
-			fallbackPath := filepath.Join(dest, "rticonnextdds-connector", "lib", fallback)
-			if _, err := os.Stat(fallbackPath); err == nil {
-				fmt.Printf("  Status: ✅ Libraries installed (using fallback %s)\n", fallback)
-				fmt.Printf("  Fallback path: %s\n", fallbackPath)
-				libPath = fallbackPath // Use fallback path for listing files
-			} else {
-				fmt.Printf("  Status: ❌ No libraries found\n")
-				fmt.Printf("  Run: go run github.com/rticommunity/rticonnextdds-connector-go/cmd/download-libs@latest\n")
-				return
-			}
+	if !inst.Installed {
+		fmt.Fprintf(out, "  Status: ❌ No libraries found\n")
+		fmt.Fprintf(out, "  Run: go run github.com/rticommunity/rticonnextdds-connector-go/cmd/download-libs@latest\n")
+		return result, fmt.Errorf("no libraries found in %s", libDir)
+	}
+	if inst.FallbackUsed {
+		fmt.Fprintf(out, "  Status: ✅ Libraries installed (using fallback %s)\n", inst.Fallback)
+	} else {
+		fmt.Fprintf(out, "  Status: ✅ Libraries installed\n")
+	}
+
+	fmt.Fprintf(out, "  Libraries:\n")
+	for _, file := range inst.Files {
+		if file.Link != "" {
+			fmt.Fprintf(out, "    %s -> %s\n", file.Name, file.Link)
 		} else {
-			fmt.Printf("  Status: ❌ No libraries found\n")
-			fmt.Printf("  Run: go run github.com/rticommunity/rticonnextdds-connector-go/cmd/download-libs@latest\n")
-			return
+			fmt.Fprintf(out, "    %s (%d bytes)\n", file.Name, file.Size)
 		}
-	} else {
-		fmt.Printf("  Status: ✅ Libraries installed\n")
 	}
 
-	// List library files
-	entries, err := os.ReadDir(libPath)
+	return result, nil
+}
+
+// isLibraryFile tells whether a file name is a shared library
+func isLibraryFile(name string) bool {
+	return strings.HasSuffix(name, ".so") ||
+		strings.Contains(name, ".so.") ||
+		strings.HasSuffix(name, ".dylib") ||
+		strings.HasSuffix(name, ".dll") ||
+		strings.HasSuffix(name, ".a")
+}
+
+// installedPlatform returns the platform whose libraries are used from
+// libDir: the preferred one, or the fallback if only that one is installed
+func installedPlatform(libDir string) string {
+	preferred, fallback := getPlatformWithFallback()
+	if fallback != "" {
+		if _, err := os.Stat(filepath.Join(libDir, "lib", preferred)); os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(libDir, "lib", fallback)); err == nil {
+				return fallback
+			}
+		}
+	}
+	return preferred
+}
+
+// installedPlatforms returns the platforms installed in libDir
+func installedPlatforms(libDir string) []string {
+	entries, err := os.ReadDir(filepath.Join(libDir, "lib"))
 	if err != nil {
-		fmt.Printf("  Error reading directory: %v\n", err)
-		return
+		return nil
 	}
 
-	fmt.Printf("  Libraries:\n")
+	var platforms []string
 	for _, entry := range entries {
-		if !entry.IsDir() && (strings.HasSuffix(entry.Name(), ".so") ||
-			strings.HasSuffix(entry.Name(), ".dylib") ||
-			strings.HasSuffix(entry.Name(), ".dll")) {
+		if entry.IsDir() {
+			platforms = append(platforms, entry.Name())
+		}
+	}
+	return platforms
+}
+
+func downloadLibraries(version string, opts installOptions) error {
+	preferred, fallback := getPlatformWithFallback()
+	libDir := opts.libDir()
+
+	platforms, ok := platformsToInstall(libDir, opts)
+	if !ok {
+		fmt.Fprintf(out, "⚠️  Libraries already exist at %s\n", libDir)
+		fmt.Fprintf(out, "Use -force flag to overwrite, or -current to check installation\n")
+		return nil
+	}
+	opts.platforms = platforms
+
+	fmt.Fprintf(out, "🌐 Downloading RTI Connector %s...\n", version)
+	if opts.requirePlatforms {
+		fmt.Fprintf(out, "📱 Target platforms: %s\n", strings.Join(opts.platforms, ", "))
+	} else {
+		fmt.Fprintf(out, "📱 Target platform: %s", preferred)
+		if fallback != "" {
+			fmt.Fprintf(out, " (fallback available: %s)", fallback)
+		}
+		fmt.Fprintf(out, "\n")
+	}
+
+	lock, err := readLockFile(opts.lockPath)
+	if err != nil {
+		return err
+	}
+	expected, source := pinnedChecksum(opts, lock, version, "")
+
+	// Reuse an archive downloaded by a previous run, without network access
+	// when the lock file pins its name and checksum
+	archiveName := ""
+	if lock != nil && lock.Version == version {
+		archiveName = lock.Archive
+	}
+	archivePath := findCachedArchive(opts.cacheDir, version, archiveName)
+	downloaded := false
+	if archivePath != "" {
+		fmt.Fprintf(out, "📦 Using cached archive: %s\n", archivePath)
+	}
 
-			info, err := entry.Info()
-			if err == nil {
-				fmt.Printf("    %s (%d bytes)\n", entry.Name(), info.Size())
+	if archivePath == "" || expected == "" {
+		// Get the actual download URL from GitHub API
+		release, err := getRelease(version)
+		if err != nil && archivePath == "" {
+			return fmt.Errorf("finding download URL: %v", err)
+		}
+
+		if err == nil {
+			asset, err := findArchiveAsset(release, opts.static)
+			if err != nil {
+				return fmt.Errorf("finding download URL: %v", err)
+			}
+			if archivePath == "" {
+				archiveName = asset.Name
+				archivePath = findCachedArchive(opts.cacheDir, version, archiveName)
+			}
+
+			if expected == "" {
+				expected, err = fetchReleaseChecksum(release, archiveName)
+				if err != nil {
+					return err
+				}
+				source = "release " + version
+			}
+
+			if archivePath == "" {
+				fmt.Fprintf(out, "📦 Downloading: %s\n", asset.Name)
+				archivePath, err = downloadArchive(asset.BrowserDownloadURL, asset.Name, version, opts.cacheDir)
+				if err != nil {
+					return err
+				}
+				downloaded = true
 			} else {
-				fmt.Printf("    %s\n", entry.Name())
+				fmt.Fprintf(out, "📦 Using cached archive: %s\n", archivePath)
 			}
 		}
 	}
+	if opts.cacheDir == "" && downloaded {
+		defer os.Remove(archivePath)
+	}
+
+	actual, err := verifyChecksum(archivePath, archiveName, expected, source, opts.allowUnpinned)
+	if err != nil {
+		// Never keep an archive that failed verification in the cache
+		if opts.cacheDir != "" {
+			os.Remove(archivePath)
+		}
+		return err
+	}
+
+	if err := extractArchive(archivePath, libDir, opts.platforms, opts.requirePlatforms, opts.requiredLibraries()); err != nil {
+		return err
+	}
+
+	return updateLockFile(opts.lockPath, libDir, LockFile{
+		Version: version,
+		Archive: archiveName,
+		SHA256:  actual,
+	})
 }
 
-func downloadLibraries(version, dest string, force bool) error {
-	preferred, fallback := getPlatformWithFallback()
-	libDir := filepath.Join(dest, "rticonnextdds-connector")
-
-	// Check if libraries already exist
-	if !force {
-		if _, err := os.Stat(libDir); err == nil {
-			fmt.Printf("⚠️  Libraries already exist at %s\n", libDir)
-			fmt.Printf("Use -force flag to overwrite, or -current to check installation\n")
-			return nil
+// platformsToInstall returns the platforms whose libraries must be installed
+// in libDir, or false if the requested ones already are: the platforms given
+// with -platform that are missing, or the host platform if it is missing.
+// -force reinstalls every requested platform, and -all every platform of the
+// archive.
+func platformsToInstall(libDir string, opts installOptions) ([]string, bool) {
+	if opts.force || opts.platforms == nil {
+		return opts.platforms, true
+	}
+
+	var missing []string
+	for _, platform := range opts.platforms {
+		if _, err := os.Stat(filepath.Join(libDir, "lib", platform)); err != nil {
+			missing = append(missing, platform)
 		}
 	}
+	// Without -platform, the libraries of the host are usable if either its
+	// preferred platform or the fallback is installed
+	if len(missing) == 0 || (!opts.requirePlatforms && len(missing) < len(opts.platforms)) {
+		return nil, false
+	}
+	return missing, true
+}
 
-	fmt.Printf("🌐 Downloading RTI Connector %s...\n", version)
-	fmt.Printf("📱 Target platform: %s", preferred)
-	if fallback != "" {
-		fmt.Printf(" (fallback available: %s)", fallback)
+// installArchive installs the libraries of an archive already on disk, for
+// machines without access to GitHub or a mirror
+func installArchive(archivePath, version string, opts installOptions) error {
+	libDir := opts.libDir()
+
+	if _, err := os.Stat(archivePath); err != nil {
+		return fmt.Errorf("reading archive: %v", err)
+	}
+
+	platforms, ok := platformsToInstall(libDir, opts)
+	if !ok {
+		fmt.Fprintf(out, "⚠️  Libraries already exist at %s\n", libDir)
+		fmt.Fprintf(out, "Use -force flag to overwrite, or -current to check installation\n")
+		return nil
 	}
-	fmt.Printf("\n")
+	opts.platforms = platforms
 
-	// Get the actual download URL from GitHub API
-	downloadURL, archiveName, err := getDownloadURL(version)
+	lock, err := readLockFile(opts.lockPath)
 	if err != nil {
-		return fmt.Errorf("finding download URL: %v", err)
+		return err
+	}
+	archiveName := filepath.Base(archivePath)
+	expected, source := pinnedChecksum(opts, lock, version, archiveName)
+	if version == "" && lock != nil && lock.Archive == archiveName {
+		version = lock.Version
 	}
 
-	fmt.Printf("📦 Downloading: %s\n", archiveName)
+	fmt.Fprintf(out, "📦 Installing from archive: %s\n", archivePath)
+	actual, err := verifyChecksum(archivePath, archiveName, expected, source, opts.allowUnpinned)
+	if err != nil {
+		return err
+	}
+
+	if err := extractArchive(archivePath, libDir, opts.platforms, opts.requirePlatforms, opts.requiredLibraries()); err != nil {
+		return err
+	}
+
+	return updateLockFile(opts.lockPath, libDir, LockFile{
+		Version: version,
+		Archive: archiveName,
+		SHA256:  actual,
+	})
+}
+
+// updateLockFile records the verified archive and the libraries installed
+// from it in libDir in the lock file
+func updateLockFile(path, libDir string, lock LockFile) error {
+	libraries, err := hashLibraries(filepath.Join(libDir, "lib"))
+	if err != nil {
+		return fmt.Errorf("computing library checksums: %v", err)
+	}
+	lock.Libraries = libraries
+	lock.Platform = strings.Join(installedPlatforms(libDir), ",")
+
+	if err := writeLockFile(path, lock); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+
+	fmt.Fprintf(out, "📝 Recorded %s in %s\n", lock.Archive, path)
+	return nil
+}
+
+// downloadArchive downloads an archive and returns its path. With a cache
+// directory the archive is stored in it, otherwise in a temporary file that
+// the caller removes.
+func downloadArchive(downloadURL, archiveName, version, cacheDir string) (string, error) {
+	tmpDir := ""
+	if cacheDir != "" {
+		tmpDir = cacheEntryDir(cacheDir, version)
+		if err := os.MkdirAll(tmpDir, 0755); err != nil {
+			return "", fmt.Errorf("creating cache directory: %v", err)
+		}
+	}
 
 	// Create temporary file
-	tmpFile, err := os.CreateTemp("", archiveName)
+	tmpFile, err := os.CreateTemp(tmpDir, archiveName+".download-*")
 	if err != nil {
-		return fmt.Errorf("creating temp file: %v", err)
+		return "", fmt.Errorf("creating temp file: %v", err)
 	}
-	defer os.Remove(tmpFile.Name())
 	defer tmpFile.Close()
 
 	// Download file
 	resp, err := http.Get(downloadURL)
 	if err != nil {
-		return fmt.Errorf("downloading file: %v", err)
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("downloading file: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: %s (check if version %s exists)", resp.Status, version)
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("download failed: %s (check if version %s exists)", resp.Status, version)
 	}
 
 	// Copy to temp file with progress
-	fmt.Printf("⬇️  Downloading...")
+	fmt.Fprintf(out, "⬇️  Downloading...")
 	_, err = io.Copy(tmpFile, resp.Body)
+	if err == nil {
+		err = tmpFile.Close()
+	}
 	if err != nil {
-		return fmt.Errorf("saving file: %v", err)
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("saving file: %v", err)
 	}
-	fmt.Printf(" Done!\n")
+	fmt.Fprintf(out, " Done!\n")
 
-	// Extract archive
-	fmt.Printf("📂 Extracting archive...\n")
-	err = extractZip(tmpFile.Name(), dest, libDir)
-	if err != nil {
-		return fmt.Errorf("extracting archive: %v", err)
+	if cacheDir == "" {
+		return tmpFile.Name(), nil
 	}
 
-	fmt.Printf("✅ Libraries installed to: %s\n", libDir)
-	return nil
+	// Only complete downloads get the archive name, so an interrupted run
+	// never leaves a truncated archive in the cache
+	archivePath := filepath.Join(tmpDir, filepath.Base(archiveName))
+	if err := os.Rename(tmpFile.Name(), archivePath); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("caching archive: %v", err)
+	}
+	fmt.Fprintf(out, "💾 Cached archive: %s\n", archivePath)
+
+	return archivePath, nil
 }
 
-func extractZip(src, dest, connectorDir string) error {
-	r, err := zip.OpenReader(src)
+// defaultCacheDir returns the per-user cache directory for archives, or an
+// empty string (no cache) when the system does not define one
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
 	if err != nil {
-		return err
+		return ""
 	}
-	defer r.Close()
+	return filepath.Join(dir, "rticonnextdds-connector-go")
+}
 
-	// Create the rticonnextdds-connector directory
-	os.MkdirAll(connectorDir, 0755)
+// cacheEntryDir returns the cache directory of the archives of a version
+func cacheEntryDir(cacheDir, version string) string {
+	return filepath.Join(cacheDir, version)
+}
 
-	// Extract files
-	for _, f := range r.File {
-		// Map the extracted path to include the connector directory
-		// The ZIP contains lib/* which we want to extract to rticonnextdds-connector/lib/*
-		path := filepath.Join(connectorDir, f.Name)
+// findCachedArchive returns the path of the cached archive archiveName of a
+// version, or an empty string if there is none. Archives are cached by name,
+// so the shared and static archives, which hold the libraries of every
+// platform, are each cached once whatever the host platform.
+func findCachedArchive(cacheDir, version, archiveName string) string {
+	if cacheDir == "" || version == "" || archiveName == "" {
+		return ""
+	}
 
-		// Check for ZipSlip vulnerability - ensure path is within the destination tree
-		if !strings.HasPrefix(path, filepath.Clean(connectorDir)+string(os.PathSeparator)) {
-			continue
-		}
+	path := filepath.Join(cacheEntryDir(cacheDir, version), filepath.Base(archiveName))
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+
+	return path
+}
+
+// finishInstall copies the libraries to installTo, if given, and shows how to
+// use them
+func finishInstall(opts installOptions, installTo string) error {
+	if opts.static {
+		showStaticInstructions(opts.libDir())
+		return nil
+	}
+	if installTo == "" {
+		showSetupInstructions(opts.dest)
+		return nil
+	}
+
+	if err := installLibrariesTo(opts.dest, installTo); err != nil {
+		return fmt.Errorf("installing libraries to %s: %v", installTo, err)
+	}
+	showInstallToInstructions(installTo)
+	return nil
+}
+
+// installLibrariesTo copies the libraries of the current platform, with their
+// version symlinks, to dir
+func installLibrariesTo(dest, dir string) error {
+	libDir := filepath.Join(dest, connectorDirName)
+	libPath := filepath.Join(libDir, "lib", installedPlatform(libDir))
+
+	entries, err := os.ReadDir(libPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
 
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.FileInfo().Mode())
+	for _, entry := range entries {
+		if entry.IsDir() || !isLibraryFile(entry.Name()) {
 			continue
 		}
 
-		// Create directories if needed
-		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		src := filepath.Join(libPath, entry.Name())
+		dst := filepath.Join(dir, entry.Name())
+		if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 
-		// Extract file
-		rc, err := f.Open()
-		if err != nil {
-			return err
+		if entry.Type()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(src)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(target, dst); err == nil {
+				continue
+			}
+			// Fall back to copying the target where links are not supported
 		}
-
-		outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.FileInfo().Mode())
-		if err != nil {
-			rc.Close()
+		if err := copyFile(src, dst); err != nil {
 			return err
 		}
+	}
 
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
+	fmt.Fprintf(out, "📦 Libraries copied to: %s\n", dir)
+	return nil
+}
 
-		if err != nil {
-			return err
-		}
+func showStaticInstructions(libDir string) {
+	fmt.Fprintln(out, "🔧 Setup Instructions:")
+	fmt.Fprintf(out, "Build with the static libraries of %s:\n", libDir)
+	if runtime.GOOS == "linux" {
+		fmt.Fprintln(out, "  go build -tags static -ldflags '-linkmode external -extldflags \"-static\"' ./...")
+		fmt.Fprintln(out, "The binaries need no shared library and can run in a scratch container.")
+	} else {
+		fmt.Fprintln(out, "  go build -tags static ./...")
 	}
+}
 
-	return nil
+func showInstallToInstructions(dir string) {
+	fmt.Fprintln(out, "🔧 Setup Instructions:")
+
+	switch runtime.GOOS {
+	case "linux":
+		fmt.Fprintf(out, "Place your binary in %s: it finds the libraries through its $ORIGIN rpath.\n", dir)
+	case "darwin":
+		fmt.Fprintf(out, "Place your binary in %s and add an rpath to it:\n", dir)
+		fmt.Fprintf(out, "  install_name_tool -add_rpath @executable_path <binary>\n")
+	case "windows":
+		fmt.Fprintf(out, "Place your binary in %s: Windows loads DLLs from the directory of the executable.\n", dir)
+	}
 }
 
 func showSetupInstructions(dest string) {
 	preferred, fallback := getPlatformWithFallback()
-	libPath := filepath.Join(dest, "rticonnextdds-connector", "lib", preferred)
+	libPath := filepath.Join(dest, connectorDirName, "lib", preferred)
 
 	// Check if preferred platform exists, otherwise use fallback
 	if _, err := os.Stat(libPath); os.IsNotExist(err) && fallback != "" {
-		fallbackPath := filepath.Join(dest, "rticonnextdds-connector", "lib", fallback)
+		fallbackPath := filepath.Join(dest, connectorDirName, "lib", fallback)
 		if _, err := os.Stat(fallbackPath); err == nil {
 			libPath = fallbackPath
-			fmt.Printf("ℹ️  Using %s libraries (fallback for %s)\n\n", fallback, preferred)
+			fmt.Fprintf(out, "ℹ️  Using %s libraries (fallback for %s)\n\n", fallback, preferred)
 		}
 	}
 
-	fmt.Println("🔧 Setup Instructions:")
-	fmt.Println("Add the following to your environment:")
+	if abs, err := filepath.Abs(libPath); err == nil {
+		libPath = abs
+	}
+
+	fmt.Fprintln(out, "🔧 Setup Instructions:")
 
 	// Determine OS for environment setup
 	switch runtime.GOOS {
 	case "linux":
-		fmt.Printf("export LD_LIBRARY_PATH=%s:$LD_LIBRARY_PATH\n", libPath)
+		fmt.Fprintln(out, "Add the following to your environment:")
+		fmt.Fprintf(out, "export LD_LIBRARY_PATH=%s:$LD_LIBRARY_PATH\n", libPath)
+		fmt.Fprintln(out, "Outside this module (e.g., with go get), also point cgo at the libraries:")
+		fmt.Fprintf(out, "export CGO_LDFLAGS=-L%s\n", libPath)
 	case "darwin":
-		fmt.Printf("export DYLD_LIBRARY_PATH=%s:$DYLD_LIBRARY_PATH\n", libPath)
+		fmt.Fprintln(out, "Add the following to your environment:")
+		fmt.Fprintf(out, "export DYLD_LIBRARY_PATH=%s:$DYLD_LIBRARY_PATH\n", libPath)
+		fmt.Fprintln(out, "Outside this module (e.g., with go get), also point cgo at the libraries:")
+		fmt.Fprintf(out, "export CGO_LDFLAGS=-L%s\n", libPath)
 	case "windows":
-		fmt.Printf("set PATH=%s;%%PATH%%\n", libPath)
+		fmt.Fprintln(out, "Add the following to your environment:")
+		fmt.Fprintf(out, "set PATH=%s;%%PATH%%\n", libPath)
+		fmt.Fprintln(out, "Outside this module (e.g., with go get), also point cgo at the libraries:")
+		fmt.Fprintf(out, "set CGO_LDFLAGS=-L%s\n", libPath)
 	}
 
-	fmt.Println("\n📝 Example usage:")
-	fmt.Println("  go run your_program.go")
+	fmt.Fprintln(out, "\n📝 Example usage:")
+	fmt.Fprintln(out, "  go run your_program.go")
 }
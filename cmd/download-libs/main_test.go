@@ -0,0 +1,433 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testVersion = "v9.9.9"
+
+// newTestArchive returns a release archive containing a library for the host platform
+func newTestArchive(t *testing.T) []byte {
+	preferred, _ := getPlatformWithFallback()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("lib/" + preferred + "/librtiddsconnector.so")
+	assert.Nil(t, err)
+	_, err = f.Write([]byte("library"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newTestServer stands in for the GitHub releases API and its download URLs.
+// The release publishes checksum as "<archive>.sha256" unless it is empty.
+func newTestServer(t *testing.T, archive []byte, checksum string) (*httptest.Server, *int) {
+	downloads := 0
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	archiveName := "connector-" + testVersion + ".zip"
+	release := Release{TagName: testVersion, Name: testVersion}
+	release.Assets = append(release.Assets, Asset{
+		Name:               archiveName,
+		BrowserDownloadURL: server.URL + "/download/" + archiveName,
+	})
+	if checksum != "" {
+		release.Assets = append(release.Assets, Asset{
+			Name:               archiveName + ".sha256",
+			BrowserDownloadURL: server.URL + "/download/" + archiveName + ".sha256",
+		})
+		mux.HandleFunc("/download/"+archiveName+".sha256", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(checksum + "  " + archiveName + "\n"))
+		})
+	}
+
+	mux.HandleFunc("/releases", func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, json.NewEncoder(w).Encode([]Release{release}))
+	})
+	mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, json.NewEncoder(w).Encode(release))
+	})
+	mux.HandleFunc("/releases/tags/"+testVersion, func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, json.NewEncoder(w).Encode(release))
+	})
+	mux.HandleFunc("/download/"+archiveName, func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(archive)
+	})
+
+	return server, &downloads
+}
+
+// useAPI points the tool at url for the duration of a test
+func useAPI(t *testing.T, url string) {
+	previous := apiURL
+	apiURL = url
+	t.Cleanup(func() { apiURL = previous })
+}
+
+// testOptions installs to dest with a lock file in dest and no cache. The
+// test archives have no published checksum, so they are trusted on first use.
+func testOptions(dest string) installOptions {
+	return installOptions{
+		dest:          dest,
+		lockPath:      filepath.Join(dest, lockFileName),
+		allowUnpinned: true,
+	}
+}
+
+// checkError returns the error of -check on the libraries of opts
+func checkError(opts installOptions) error {
+	_, err := checkLibraries(opts.libDir(), opts.lockPath)
+	return err
+}
+
+func installedLibrary(dest string) string {
+	preferred, _ := getPlatformWithFallback()
+	return filepath.Join(dest, "rticonnextdds-connector", "lib", preferred, "librtiddsconnector.so")
+}
+
+func TestMirror(t *testing.T) {
+	server, _ := newTestServer(t, newTestArchive(t), "")
+	defer server.Close()
+	useAPI(t, server.URL)
+
+	version, err := getLatestVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, testVersion, version)
+
+	dest := t.TempDir()
+	assert.Nil(t, downloadLibraries(version, testOptions(dest)))
+	content, err := os.ReadFile(installedLibrary(dest))
+	assert.Nil(t, err)
+	assert.Equal(t, "library", string(content))
+
+	_, err = getRelease("v0.0.0")
+	assert.NotNil(t, err)
+}
+
+// Tests that a second download of the same version reuses the cached archive
+// without contacting the server
+func TestCache(t *testing.T) {
+	server, downloads := newTestServer(t, newTestArchive(t), "")
+	useAPI(t, server.URL)
+	cacheDir := t.TempDir()
+
+	opts := testOptions(t.TempDir())
+	opts.cacheDir = cacheDir
+	assert.Nil(t, downloadLibraries(testVersion, opts))
+	assert.Equal(t, 1, *downloads)
+
+	archiveName := "connector-" + testVersion + ".zip"
+	cached := findCachedArchive(cacheDir, testVersion, archiveName)
+	assert.Equal(t, filepath.Join(cacheDir, testVersion, archiveName), cached)
+
+	// Simulate an air-gapped machine
+	server.Close()
+
+	opts.dest = t.TempDir()
+	assert.Nil(t, downloadLibraries(testVersion, opts))
+	assert.Equal(t, 1, *downloads)
+	_, err := os.Stat(installedLibrary(opts.dest))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "", findCachedArchive(cacheDir, "v0.0.0", archiveName))
+	assert.Equal(t, "", findCachedArchive(cacheDir, testVersion, "connector-static.zip"))
+	assert.Equal(t, "", findCachedArchive("", testVersion, archiveName))
+}
+
+// Tests that only the requested platforms that are missing are installed
+func TestInstallPlatforms(t *testing.T) {
+	useAPI(t, "http://127.0.0.1:0")
+
+	var entries []testEntry
+	for _, platform := range []string{"linux-x64", "linux-arm64", "linux-arm"} {
+		entries = append(entries, testEntry{name: "lib/" + platform + "/librtiddsconnector.so", content: platform, mode: 0666})
+	}
+	archivePath := writeTestZip(t, entries)
+
+	opts := testOptions(t.TempDir())
+	opts.platforms = []string{"linux-x64"}
+	opts.requirePlatforms = true
+	assert.Nil(t, installArchive(archivePath, testVersion, opts))
+
+	libDir := opts.libDir()
+	platforms, ok := platformsToInstall(libDir, opts)
+	assert.False(t, ok)
+	assert.Nil(t, platforms)
+
+	opts.platforms = []string{"linux-x64", "linux-arm64"}
+	platforms, ok = platformsToInstall(libDir, opts)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"linux-arm64"}, platforms)
+
+	assert.Nil(t, installArchive(archivePath, testVersion, opts))
+	for _, platform := range []string{"linux-x64", "linux-arm64"} {
+		_, err := os.Stat(filepath.Join(libDir, "lib", platform, "librtiddsconnector.so"))
+		assert.Nil(t, err)
+	}
+	_, err := os.Stat(filepath.Join(libDir, "lib", "linux-arm"))
+	assert.True(t, os.IsNotExist(err))
+
+	// The host platform is not installed again if its fallback is
+	opts.platforms = []string{"linux-arm", "linux-x64"}
+	opts.requirePlatforms = false
+	_, ok = platformsToInstall(libDir, opts)
+	assert.False(t, ok)
+
+	opts.force = true
+	platforms, ok = platformsToInstall(libDir, opts)
+	assert.True(t, ok)
+	assert.Equal(t, opts.platforms, platforms)
+}
+
+func TestFromArchive(t *testing.T) {
+	// No server: installing from an archive must not use the network
+	useAPI(t, "http://127.0.0.1:0")
+
+	archivePath := filepath.Join(t.TempDir(), "connector.zip")
+	assert.Nil(t, os.WriteFile(archivePath, newTestArchive(t), 0644))
+
+	opts := testOptions(t.TempDir())
+	assert.Nil(t, installArchive(archivePath, "", opts))
+	_, err := os.Stat(installedLibrary(opts.dest))
+	assert.Nil(t, err)
+
+	opts.force = true
+	err = installArchive(filepath.Join(t.TempDir(), "missing.zip"), "", opts)
+	assert.NotNil(t, err)
+}
+
+// Tests that the checksum published with a release is verified and that a
+// mismatch refuses the archive, and does not leave it in the cache
+func TestReleaseChecksum(t *testing.T) {
+	archive := newTestArchive(t)
+	server, _ := newTestServer(t, archive, sha256Hex(archive))
+	defer server.Close()
+	useAPI(t, server.URL)
+
+	opts := testOptions(t.TempDir())
+	assert.Nil(t, downloadLibraries(testVersion, opts))
+
+	preferred, _ := getPlatformWithFallback()
+	lock, err := readLockFile(opts.lockPath)
+	assert.Nil(t, err)
+	assert.Equal(t, &LockFile{
+		Version:  testVersion,
+		Platform: preferred,
+		Archive:  "connector-" + testVersion + ".zip",
+		SHA256:   sha256Hex(archive),
+		Libraries: map[string]string{
+			preferred + "/librtiddsconnector.so": sha256Hex([]byte("library")),
+		},
+	}, lock)
+
+	tampered, _ := newTestServer(t, archive, sha256Hex([]byte("something else")))
+	defer tampered.Close()
+	useAPI(t, tampered.URL)
+
+	opts = testOptions(t.TempDir())
+	opts.cacheDir = t.TempDir()
+	err = downloadLibraries(testVersion, opts)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	_, err = os.Stat(installedLibrary(opts.dest))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, "", findCachedArchive(opts.cacheDir, testVersion, "connector-"+testVersion+".zip"))
+}
+
+// Tests that the checksum recorded in the lock file is enforced on later
+// installs, and that archives without a checksum are refused unless
+// -allow-unpinned is given
+func TestLockFile(t *testing.T) {
+	useAPI(t, "http://127.0.0.1:0")
+
+	archivePath := filepath.Join(t.TempDir(), "connector.zip")
+	archive := newTestArchive(t)
+	assert.Nil(t, os.WriteFile(archivePath, archive, 0644))
+
+	opts := testOptions(t.TempDir())
+	opts.allowUnpinned = false
+	err := installArchive(archivePath, testVersion, opts)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "-allow-unpinned")
+	_, err = os.Stat(installedLibrary(opts.dest))
+	assert.True(t, os.IsNotExist(err))
+
+	// -allow-unpinned pins the checksum
+	opts.allowUnpinned = true
+	assert.Nil(t, installArchive(archivePath, testVersion, opts))
+	lock, err := readLockFile(opts.lockPath)
+	assert.Nil(t, err)
+	assert.Equal(t, sha256Hex(archive), lock.SHA256)
+
+	// Once pinned, the archive installs without -allow-unpinned
+	opts.allowUnpinned = false
+	opts.force = true
+	assert.Nil(t, installArchive(archivePath, "", opts))
+
+	// A different archive with the same name no longer installs
+	assert.Nil(t, os.WriteFile(archivePath, []byte("tampered"), 0644))
+	err = installArchive(archivePath, "", opts)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), lockFileName)
+
+	// An explicit -sha256 takes precedence over the lock file
+	opts.checksum = sha256Hex([]byte("tampered"))
+	assert.NotNil(t, installArchive(archivePath, "", opts))
+}
+
+// Tests that -check detects libraries modified, removed or added after the
+// lock file was written
+func TestCheckLibraries(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "connector.zip")
+	assert.Nil(t, os.WriteFile(archivePath, newTestArchive(t), 0644))
+
+	opts := testOptions(t.TempDir())
+	assert.NotNil(t, checkError(opts))
+
+	assert.Nil(t, installArchive(archivePath, testVersion, opts))
+	assert.Nil(t, checkError(opts))
+	assert.Equal(t, testVersion, lockedVersion(opts.lockPath))
+
+	library := installedLibrary(opts.dest)
+	assert.Nil(t, os.WriteFile(library, []byte("other build"), 0644))
+	drift, err := checkLibraries(opts.libDir(), opts.lockPath)
+	assert.NotNil(t, err)
+	preferred, _ := getPlatformWithFallback()
+	assert.Equal(t, []string{"modified: " + preferred + "/librtiddsconnector.so"}, drift)
+
+	assert.Nil(t, os.Remove(library))
+	assert.NotNil(t, checkError(opts))
+
+	opts.force = true
+	assert.Nil(t, installArchive(archivePath, testVersion, opts))
+	extra := filepath.Join(filepath.Dir(library), "libextra.so")
+	assert.Nil(t, os.WriteFile(extra, []byte("extra"), 0644))
+	assert.NotNil(t, checkError(opts))
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := sha256Hex([]byte("archive"))
+
+	actual, err := parseChecksum(strings.NewReader(sum+"\n"), "a.zip")
+	assert.Nil(t, err)
+	assert.Equal(t, sum, actual)
+
+	list := sha256Hex([]byte("other")) + "  b.zip\n" + strings.ToUpper(sum) + " *a.zip\n"
+	actual, err = parseChecksum(strings.NewReader(list), "a.zip")
+	assert.Nil(t, err)
+	assert.Equal(t, sum, actual)
+
+	_, err = parseChecksum(strings.NewReader(list), "c.zip")
+	assert.NotNil(t, err)
+
+	_, err = parseChecksum(strings.NewReader("not-a-checksum\n"), "a.zip")
+	assert.NotNil(t, err)
+}
+
+func TestFindArchiveAsset(t *testing.T) {
+	release := Release{TagName: testVersion, Assets: []Asset{
+		{Name: "connector-static.tar.gz"},
+		{Name: "connector.tar.gz"},
+		{Name: "connector.zip"},
+		{Name: "connector.zip.sha256"},
+	}}
+
+	asset, err := findArchiveAsset(release, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "connector.zip", asset.Name)
+
+	asset, err = findArchiveAsset(release, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "connector-static.tar.gz", asset.Name)
+
+	release.Assets = release.Assets[1:]
+	_, err = findArchiveAsset(release, true)
+	assert.NotNil(t, err)
+}
+
+// Tests that static libraries are installed and locked apart from the shared
+// ones
+func TestStaticInstall(t *testing.T) {
+	preferred, _ := getPlatformWithFallback()
+	var entries []testEntry
+	for _, name := range staticLibraries {
+		entries = append(entries, testEntry{name: "lib/" + preferred + "/" + name, content: "archive", mode: 0666})
+	}
+
+	opts := testOptions(t.TempDir())
+	opts.static = true
+	assert.Nil(t, installArchive(writeTestZip(t, entries), testVersion, opts))
+
+	_, err := os.Stat(filepath.Join(opts.dest, staticConnectorDirName, "lib", preferred, "librtiddsconnectorz.a"))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(opts.dest, connectorDirName))
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, checkError(opts))
+
+	assert.Equal(t, filepath.Join(opts.dest, staticLockFileName), defaultLockPath(opts.dest, true))
+
+	// An archive without the static libraries is rejected
+	opts = testOptions(t.TempDir())
+	opts.static = true
+	assert.NotNil(t, installArchive(writeTestZip(t, entries[1:]), testVersion, opts))
+}
+
+// Tests the results printed by -json for each mode
+func TestResults(t *testing.T) {
+	archive := newTestArchive(t)
+	server, _ := newTestServer(t, archive, "")
+	defer server.Close()
+	useAPI(t, server.URL)
+
+	result, err := listVersions()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{testVersion}, result.Versions)
+
+	opts := testOptions(t.TempDir())
+	result, err = showCurrent(opts.libDir())
+	assert.NotNil(t, err)
+	assert.False(t, result.Installation.Installed)
+
+	result, err = install("", "latest", "", opts)
+	assert.Nil(t, err)
+	assert.Equal(t, testVersion, result.Version)
+	assert.Equal(t, sha256Hex(archive), result.SHA256)
+	assert.True(t, result.Installation.Installed)
+
+	result, err = showCurrent(opts.libDir())
+	assert.Nil(t, err)
+	preferred, _ := getPlatformWithFallback()
+	assert.Equal(t, preferred, result.Installation.Platform)
+	assert.Equal(t, []InstalledFile{{
+		Name:   "librtiddsconnector.so",
+		Size:   int64(len("library")),
+		SHA256: sha256Hex([]byte("library")),
+	}}, result.Installation.Files)
+
+	data, err := json.Marshal(result)
+	assert.Nil(t, err)
+	var document map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &document))
+	assert.Contains(t, document["installation"], "fallback_used")
+	assert.NotContains(t, document, "error")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// out receives the human readable output. With -json it is stderr, so that
+// stdout only holds the JSON document.
+var out io.Writer = os.Stdout
+
+// Result is the document printed on stdout by -json, whatever the mode
+type Result struct {
+	Versions     []string      `json:"versions,omitempty"`
+	Version      string        `json:"version,omitempty"`
+	Archive      string        `json:"archive,omitempty"`
+	SHA256       string        `json:"sha256,omitempty"`
+	Installation *Installation `json:"installation,omitempty"`
+	InstalledTo  string        `json:"installed_to,omitempty"`
+	Drift        []string      `json:"drift,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// Installation describes the libraries installed for the current platform
+type Installation struct {
+	Platform     string          `json:"platform"`
+	Fallback     string          `json:"fallback,omitempty"`
+	FallbackUsed bool            `json:"fallback_used"`
+	LibraryPath  string          `json:"library_path"`
+	Installed    bool            `json:"installed"`
+	Files        []InstalledFile `json:"files,omitempty"`
+}
+
+// InstalledFile is an installed library. Size and SHA256 are those of the
+// target of a link.
+type InstalledFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Link   string `json:"link,omitempty"`
+}
+
+// currentInstallation returns the libraries of libDir used on this platform
+func currentInstallation(libDir string) (Installation, error) {
+	preferred, fallback := getPlatformWithFallback()
+	platform := installedPlatform(libDir)
+	inst := Installation{
+		Platform:     preferred,
+		Fallback:     fallback,
+		FallbackUsed: platform != preferred,
+		LibraryPath:  filepath.Join(libDir, "lib", platform),
+	}
+
+	entries, err := os.ReadDir(inst.LibraryPath)
+	if os.IsNotExist(err) {
+		return inst, nil
+	}
+	if err != nil {
+		return inst, err
+	}
+	inst.Installed = true
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isLibraryFile(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(inst.LibraryPath, entry.Name())
+		file := InstalledFile{Name: entry.Name()}
+		if entry.Type()&os.ModeSymlink != 0 {
+			if file.Link, err = os.Readlink(path); err != nil {
+				return inst, err
+			}
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return inst, err
+		}
+		file.Size = info.Size()
+		if file.SHA256, err = fileSHA256(path); err != nil {
+			return inst, err
+		}
+		inst.Files = append(inst.Files, file)
+	}
+
+	return inst, nil
+}
+
+func printJSON(result Result) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+}
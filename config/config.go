@@ -0,0 +1,165 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package config builds RTI Connector XML configurations from Go code.
+//
+// A Config mirrors the XML dialect read by rti.NewConnector: QoS libraries,
+// types, domain libraries and participant libraries with their publishers,
+// subscribers, writers and readers. It is marshalled to XML with Marshal, or
+// to a str:// URL with URL, and can be passed directly to
+// rti.NewConnectorFromConfig:
+//
+//	cfg := &config.Config{
+//		Types: &config.Types{Structs: []config.Struct{{
+//			Name: "ShapeType",
+//			Members: []config.Member{
+//				{Name: "color", Type: config.String, StringMaxLength: 128, Key: true},
+//				{Name: "x", Type: config.Int32},
+//			},
+//		}}},
+//		DomainLibraries: []config.DomainLibrary{{
+//			Name: "MyDomainLibrary",
+//			Domains: []config.Domain{{
+//				Name:          "MyDomain",
+//				RegisterTypes: []config.RegisterType{{Name: "ShapeType", TypeRef: "ShapeType"}},
+//				Topics:        []config.Topic{{Name: "Square", RegisterTypeRef: "ShapeType"}},
+//			}},
+//		}},
+//		ParticipantLibraries: []config.ParticipantLibrary{{
+//			Name: "MyParticipantLibrary",
+//			Participants: []config.Participant{{
+//				Name:      "Zero",
+//				DomainRef: "MyDomainLibrary::MyDomain",
+//				Publishers: []config.Publisher{{
+//					Name:    "MyPublisher",
+//					Writers: []config.DataWriter{{Name: "MySquareWriter", TopicRef: "Square"}},
+//				}},
+//			}},
+//		}},
+//	}
+//	connector, err := rti.NewConnectorFromConfig("MyParticipantLibrary::Zero", cfg)
+package config
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+/********
+* Types *
+*********/
+
+// Config is the root <dds> element of an XML configuration
+type Config struct {
+	XMLName              xml.Name             `xml:"dds"`
+	QoSLibraries         []QoSLibrary         `xml:"qos_library"`
+	Types                *Types               `xml:"types,omitempty"`
+	DomainLibraries      []DomainLibrary      `xml:"domain_library"`
+	ParticipantLibraries []ParticipantLibrary `xml:"domain_participant_library"`
+}
+
+// DomainLibrary is a named set of domains
+type DomainLibrary struct {
+	Name    string   `xml:"name,attr"`
+	Domains []Domain `xml:"domain"`
+}
+
+// Domain registers types and defines topics for a domain ID
+type Domain struct {
+	Name          string         `xml:"name,attr"`
+	DomainID      int            `xml:"domain_id,attr"`
+	RegisterTypes []RegisterType `xml:"register_type"`
+	Topics        []Topic        `xml:"topic"`
+}
+
+// RegisterType registers a type of the types section under a name
+type RegisterType struct {
+	Name    string `xml:"name,attr"`
+	TypeRef string `xml:"type_ref,attr"`
+}
+
+// Topic is a topic of a registered type
+type Topic struct {
+	Name            string `xml:"name,attr"`
+	RegisterTypeRef string `xml:"register_type_ref,attr"`
+}
+
+// ParticipantLibrary is a named set of participants, one of which is
+// instantiated by a Connector through its configuration name
+// ("Library::Participant")
+type ParticipantLibrary struct {
+	Name         string        `xml:"name,attr"`
+	Participants []Participant `xml:"domain_participant"`
+}
+
+// Participant is a domain participant and its publishers and subscribers
+type Participant struct {
+	Name      string `xml:"name,attr"`
+	DomainRef string `xml:"domain_ref,attr"`
+	// DomainID overrides the domain ID of DomainRef when set
+	DomainID    *int         `xml:"domain_id,attr,omitempty"`
+	QoS         *QoS         `xml:"participant_qos,omitempty"`
+	Publishers  []Publisher  `xml:"publisher"`
+	Subscribers []Subscriber `xml:"subscriber"`
+}
+
+// Publisher groups data writers, which are the Outputs of a Connector
+// ("Publisher::DataWriter")
+type Publisher struct {
+	Name    string       `xml:"name,attr"`
+	QoS     *QoS         `xml:"publisher_qos,omitempty"`
+	Writers []DataWriter `xml:"data_writer"`
+}
+
+// DataWriter is a writer of a topic
+type DataWriter struct {
+	Name     string `xml:"name,attr"`
+	TopicRef string `xml:"topic_ref,attr"`
+	QoS      *QoS   `xml:"datawriter_qos,omitempty"`
+}
+
+// Subscriber groups data readers, which are the Inputs of a Connector
+// ("Subscriber::DataReader")
+type Subscriber struct {
+	Name    string       `xml:"name,attr"`
+	QoS     *QoS         `xml:"subscriber_qos,omitempty"`
+	Readers []DataReader `xml:"data_reader"`
+}
+
+// DataReader is a reader of a topic
+type DataReader struct {
+	Name     string `xml:"name,attr"`
+	TopicRef string `xml:"topic_ref,attr"`
+	QoS      *QoS   `xml:"datareader_qos,omitempty"`
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// Marshal is a function to encode the configuration as XML
+func (cfg *Config) Marshal() ([]byte, error) {
+	if cfg == nil {
+		return nil, errors.New("config is null")
+	}
+
+	return xml.MarshalIndent(cfg, "", "  ")
+}
+
+// URL is a function to encode the configuration as a str:// URL, as accepted
+// by rti.NewConnector
+func (cfg *Config) URL() (string, error) {
+	data, err := cfg.Marshal()
+	if err != nil {
+		return "", err
+	}
+
+	return `str://"` + string(data) + `"`, nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newShapeConfig() *Config {
+	depth := 10
+	return &Config{
+		QoSLibraries: []QoSLibrary{{
+			Name: "QosLibrary",
+			Profiles: []QoSProfile{{
+				Name:         "DefaultProfile",
+				BaseName:     "BuiltinQosLibExp::Generic.StrictReliable",
+				IsDefaultQoS: true,
+				DataWriterQoS: &QoS{
+					Deadline: &Deadline{Period: NewDuration(1500 * time.Millisecond)},
+				},
+				DataReaderQoS: &QoS{
+					History: &History{Kind: KeepLastHistory, Depth: depth},
+					Raw:     "<deadline><period><sec>1</sec><nanosec>0</nanosec></period></deadline>",
+				},
+			}},
+		}},
+		Types: &Types{
+			Enums: []Enum{{
+				Name:        "ShapeFillKind",
+				Enumerators: []Enumerator{{Name: "SOLID_FILL"}, {Name: "HATCHED_FILL"}},
+			}},
+			Structs: []Struct{{
+				Name:          "ShapeType",
+				Extensibility: Extensible,
+				Members: []Member{
+					{Name: "color", Type: String, StringMaxLength: 128, Key: true},
+					{Name: "x", Type: Int32},
+					{Name: "fill", Type: NonBasic, NonBasicTypeName: "ShapeFillKind"},
+				},
+			}},
+		},
+		DomainLibraries: []DomainLibrary{{
+			Name: "MyDomainLibrary",
+			Domains: []Domain{{
+				Name:          "MyDomain",
+				RegisterTypes: []RegisterType{{Name: "ShapeType", TypeRef: "ShapeType"}},
+				Topics:        []Topic{{Name: "Square", RegisterTypeRef: "ShapeType"}},
+			}},
+		}},
+		ParticipantLibraries: []ParticipantLibrary{{
+			Name: "MyParticipantLibrary",
+			Participants: []Participant{{
+				Name:      "Zero",
+				DomainRef: "MyDomainLibrary::MyDomain",
+				QoS:       &QoS{Discovery: &Discovery{InitialPeers: []string{"shmem://"}}},
+				Publishers: []Publisher{{
+					Name:    "MyPublisher",
+					QoS:     &QoS{Partition: &Partition{Names: []string{"tenant-a"}}},
+					Writers: []DataWriter{{Name: "MySquareWriter", TopicRef: "Square"}},
+				}},
+				Subscribers: []Subscriber{{
+					Name: "MySubscriber",
+					Readers: []DataReader{{
+						Name:     "MySquareReader",
+						TopicRef: "Square",
+						QoS:      &QoS{Reliability: &Reliability{Kind: BestEffortReliability}},
+					}},
+				}},
+			}},
+		}},
+	}
+}
+
+// compactXML removes the indentation of an XML document
+func compactXML(s string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(s, "\n") {
+		b.WriteString(strings.TrimSpace(line))
+	}
+	return b.String()
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := newShapeConfig().Marshal()
+	assert.Nil(t, err)
+
+	expected := `<dds>
+<qos_library name="QosLibrary">
+<qos_profile name="DefaultProfile" base_name="BuiltinQosLibExp::Generic.StrictReliable" is_default_qos="true">
+<datawriter_qos><deadline><period><sec>1</sec><nanosec>500000000</nanosec></period></deadline></datawriter_qos>
+<datareader_qos>
+<history><kind>KEEP_LAST_HISTORY_QOS</kind><depth>10</depth></history>
+<deadline><period><sec>1</sec><nanosec>0</nanosec></period></deadline>
+</datareader_qos>
+</qos_profile>
+</qos_library>
+<types>
+<enum name="ShapeFillKind"><enumerator name="SOLID_FILL"></enumerator><enumerator name="HATCHED_FILL"></enumerator></enum>
+<struct name="ShapeType" extensibility="extensible">
+<member name="color" type="string" stringMaxLength="128" key="true"></member>
+<member name="x" type="int32"></member>
+<member name="fill" type="nonBasic" nonBasicTypeName="ShapeFillKind"></member>
+</struct>
+</types>
+<domain_library name="MyDomainLibrary">
+<domain name="MyDomain" domain_id="0">
+<register_type name="ShapeType" type_ref="ShapeType"></register_type>
+<topic name="Square" register_type_ref="ShapeType"></topic>
+</domain>
+</domain_library>
+<domain_participant_library name="MyParticipantLibrary">
+<domain_participant name="Zero" domain_ref="MyDomainLibrary::MyDomain">
+<participant_qos><discovery><initial_peers><element>shmem://</element></initial_peers></discovery></participant_qos>
+<publisher name="MyPublisher">
+<publisher_qos><partition><name><element>tenant-a</element></name></partition></publisher_qos>
+<data_writer name="MySquareWriter" topic_ref="Square"></data_writer>
+</publisher>
+<subscriber name="MySubscriber">
+<data_reader name="MySquareReader" topic_ref="Square">
+<datareader_qos><reliability><kind>BEST_EFFORT_RELIABILITY_QOS</kind></reliability></datareader_qos>
+</data_reader>
+</subscriber>
+</domain_participant>
+</domain_participant_library>
+</dds>`
+	assert.Equal(t, compactXML(expected), compactXML(string(data)))
+
+	// The document is well formed
+	var root struct {
+		XMLName xml.Name
+	}
+	assert.Nil(t, xml.Unmarshal(data, &root))
+	assert.Equal(t, "dds", root.XMLName.Local)
+}
+
+func TestURL(t *testing.T) {
+	url, err := newShapeConfig().URL()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(url, `str://"<dds>`))
+	assert.True(t, strings.HasSuffix(url, `</dds>"`))
+
+	// Values are escaped
+	cfg := newShapeConfig()
+	cfg.ParticipantLibraries[0].Participants[0].Publishers[0].QoS.Partition.Names = []string{`a<b>&"c"`}
+	url, err = cfg.URL()
+	assert.Nil(t, err)
+	assert.Contains(t, url, "<element>a&lt;b&gt;&amp;&#34;c&#34;</element>")
+
+	// The default partition replaces the partitions of the base profile
+	cfg.ParticipantLibraries[0].Participants[0].Publishers[0].QoS.Partition.Names = nil
+	url, err = cfg.URL()
+	assert.Nil(t, err)
+	assert.Contains(t, compactXML(url), "<partition><name></name></partition>")
+
+	var nilConfig *Config
+	_, err = nilConfig.URL()
+	assert.NotNil(t, err)
+}
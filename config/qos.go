@@ -0,0 +1,157 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+package config
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+/********
+* Types *
+*********/
+
+// QoSLibrary is a named set of QoS profiles
+type QoSLibrary struct {
+	Name     string       `xml:"name,attr"`
+	Profiles []QoSProfile `xml:"qos_profile"`
+}
+
+// QoSProfile holds the QoS of each kind of entity. A profile with
+// IsDefaultQoS applies to every entity without an explicit profile.
+type QoSProfile struct {
+	Name           string `xml:"name,attr"`
+	BaseName       string `xml:"base_name,attr,omitempty"`
+	IsDefaultQoS   bool   `xml:"is_default_qos,attr,omitempty"`
+	ParticipantQoS *QoS   `xml:"participant_qos,omitempty"`
+	PublisherQoS   *QoS   `xml:"publisher_qos,omitempty"`
+	SubscriberQoS  *QoS   `xml:"subscriber_qos,omitempty"`
+	DataWriterQoS  *QoS   `xml:"datawriter_qos,omitempty"`
+	DataReaderQoS  *QoS   `xml:"datareader_qos,omitempty"`
+}
+
+// QoS holds the QoS policies of an entity. BaseName inherits from a profile
+// ("Library::Profile"), and policies without a field here can be given as raw
+// XML elements in Raw.
+type QoS struct {
+	BaseName    string       `xml:"base_name,attr,omitempty"`
+	Reliability *Reliability `xml:"reliability,omitempty"`
+	History     *History     `xml:"history,omitempty"`
+	Durability  *Durability  `xml:"durability,omitempty"`
+	Deadline    *Deadline    `xml:"deadline,omitempty"`
+	// Partition applies to publishers and subscribers
+	Partition *Partition `xml:"partition,omitempty"`
+	// Discovery applies to participants
+	Discovery *Discovery `xml:"discovery,omitempty"`
+	Raw       string     `xml:",innerxml"`
+}
+
+// ReliabilityKind is the kind of the reliability QoS policy
+type ReliabilityKind string
+
+// HistoryKind is the kind of the history QoS policy
+type HistoryKind string
+
+// DurabilityKind is the kind of the durability QoS policy
+type DurabilityKind string
+
+const (
+	// BestEffortReliability does not repair lost samples
+	BestEffortReliability ReliabilityKind = "BEST_EFFORT_RELIABILITY_QOS"
+	// ReliableReliability repairs lost samples
+	ReliableReliability ReliabilityKind = "RELIABLE_RELIABILITY_QOS"
+
+	// KeepLastHistory keeps the last Depth samples of each instance
+	KeepLastHistory HistoryKind = "KEEP_LAST_HISTORY_QOS"
+	// KeepAllHistory keeps every sample until it is acknowledged or taken
+	KeepAllHistory HistoryKind = "KEEP_ALL_HISTORY_QOS"
+
+	// VolatileDurability does not deliver samples to late joiners
+	VolatileDurability DurabilityKind = "VOLATILE_DURABILITY_QOS"
+	// TransientLocalDurability delivers the samples in the writer history to
+	// late joiners
+	TransientLocalDurability DurabilityKind = "TRANSIENT_LOCAL_DURABILITY_QOS"
+	// TransientDurability delivers samples kept by a persistence service
+	// while it runs
+	TransientDurability DurabilityKind = "TRANSIENT_DURABILITY_QOS"
+	// PersistentDurability delivers samples kept on disk by a persistence
+	// service
+	PersistentDurability DurabilityKind = "PERSISTENT_DURABILITY_QOS"
+)
+
+// Reliability is the reliability QoS policy
+type Reliability struct {
+	Kind ReliabilityKind `xml:"kind"`
+}
+
+// History is the history QoS policy
+type History struct {
+	Kind  HistoryKind `xml:"kind"`
+	Depth int         `xml:"depth,omitempty"`
+}
+
+// Durability is the durability QoS policy
+type Durability struct {
+	Kind DurabilityKind `xml:"kind"`
+}
+
+// Deadline is the deadline QoS policy: the maximum period between the samples
+// of an instance
+type Deadline struct {
+	Period Duration `xml:"period"`
+}
+
+// Duration is a DDS duration
+type Duration struct {
+	Sec     int64 `xml:"sec"`
+	Nanosec int64 `xml:"nanosec"`
+}
+
+// Partition is the partition QoS policy of publishers and subscribers
+type Partition struct {
+	Names []string `xml:"name>element"`
+}
+
+// Discovery is the discovery QoS policy of participants
+type Discovery struct {
+	InitialPeers []string `xml:"initial_peers>element,omitempty"`
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// NewDuration is a function to convert a time.Duration to a DDS duration
+func NewDuration(d time.Duration) Duration {
+	return Duration{Sec: int64(d / time.Second), Nanosec: int64(d % time.Second)}
+}
+
+// MarshalXML is a function to marshal the partitions, with an empty <name>
+// element for the default partition so that it replaces the partitions of a
+// base profile
+func (partition Partition) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	name := xml.StartElement{Name: xml.Name{Local: "name"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(name); err != nil {
+		return err
+	}
+	for _, element := range partition.Names {
+		if err := e.EncodeElement(element, xml.StartElement{Name: xml.Name{Local: "element"}}); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeToken(name.End()); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
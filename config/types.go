@@ -0,0 +1,94 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+package config
+
+/********
+* Types *
+*********/
+
+// Types declares the data types of the configuration. Enumerations are
+// declared first, so that structures can refer to them.
+type Types struct {
+	Enums   []Enum   `xml:"enum"`
+	Structs []Struct `xml:"struct"`
+	// Raw holds other type declarations as XML (e.g., typedefs or unions)
+	Raw string `xml:",innerxml"`
+}
+
+// Extensibility is the extensibility kind of a type
+type Extensibility string
+
+const (
+	// Final types cannot change
+	Final Extensibility = "final"
+	// Extensible types can gain members at the end
+	Extensible Extensibility = "extensible"
+	// Mutable types can gain, lose and reorder members
+	Mutable Extensibility = "mutable"
+)
+
+// Struct is a structure type
+type Struct struct {
+	Name          string        `xml:"name,attr"`
+	BaseType      string        `xml:"baseType,attr,omitempty"`
+	Extensibility Extensibility `xml:"extensibility,attr,omitempty"`
+	Members       []Member      `xml:"member"`
+}
+
+// MemberType is the type of a member. Members of enumeration, structure or
+// typedef type use NonBasic and set NonBasicTypeName.
+type MemberType string
+
+// Primitive member types
+const (
+	Boolean  MemberType = "boolean"
+	Char     MemberType = "char8"
+	Octet    MemberType = "byte"
+	Int8     MemberType = "int8"
+	Uint8    MemberType = "uint8"
+	Int16    MemberType = "int16"
+	Uint16   MemberType = "uint16"
+	Int32    MemberType = "int32"
+	Uint32   MemberType = "uint32"
+	Int64    MemberType = "int64"
+	Uint64   MemberType = "uint64"
+	Float32  MemberType = "float32"
+	Float64  MemberType = "float64"
+	String   MemberType = "string"
+	WString  MemberType = "wstring"
+	NonBasic MemberType = "nonBasic"
+)
+
+// Member is a member of a structure. SequenceMaxLength makes the member a
+// sequence, and ArrayDimensions (e.g., "3,4") an array.
+type Member struct {
+	Name              string     `xml:"name,attr"`
+	Type              MemberType `xml:"type,attr"`
+	NonBasicTypeName  string     `xml:"nonBasicTypeName,attr,omitempty"`
+	StringMaxLength   int        `xml:"stringMaxLength,attr,omitempty"`
+	SequenceMaxLength int        `xml:"sequenceMaxLength,attr,omitempty"`
+	ArrayDimensions   string     `xml:"arrayDimensions,attr,omitempty"`
+	Key               bool       `xml:"key,attr,omitempty"`
+	Optional          bool       `xml:"optional,attr,omitempty"`
+}
+
+// Enum is an enumeration type
+type Enum struct {
+	Name        string       `xml:"name,attr"`
+	Enumerators []Enumerator `xml:"enumerator"`
+}
+
+// Enumerator is a value of an enumeration. Without a value, it follows the
+// previous enumerator.
+type Enumerator struct {
+	Name  string `xml:"name,attr"`
+	Value *int   `xml:"value,attr,omitempty"`
+}
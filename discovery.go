@@ -0,0 +1,219 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package rti implements functions of RTI Connector for Connext DDS in Go
+package rti
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+/********
+* Types *
+*********/
+
+// MatchedEndpoint describes a remote DataReader or DataWriter matched with
+// an Input or an Output.
+//
+// Name is the publication or subscription name. Connector Inputs and Outputs
+// are automatically assigned a name from the data_reader or data_writer name
+// in the XML configuration; endpoints without a name have an empty Name.
+//
+// GUID and InstanceHandle identify the remote endpoint and QoS holds any
+// additional QoS details reported by the native layer. They are only set when
+// the native library provides them.
+type MatchedEndpoint struct {
+	Name           string                 `json:"name"`
+	GUID           [16]byte               `json:"guid"`
+	InstanceHandle string                 `json:"instance_handle"`
+	QoS            map[string]interface{} `json:"-"`
+}
+
+// MatchEventKind tells whether a remote endpoint was matched or unmatched
+type MatchEventKind int
+
+const (
+	// Matched is reported when a remote endpoint starts matching
+	Matched MatchEventKind = iota
+	// Unmatched is reported when a remote endpoint stops matching
+	Unmatched
+)
+
+// MatchEvent is a discovery notification emitted by Input.MatchEvents and
+// Output.MatchEvents
+type MatchEvent struct {
+	Kind     MatchEventKind
+	Endpoint MatchedEndpoint
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// UnmarshalJSON decodes a matched endpoint dictionary returned by the native
+// layer. Keys other than name, guid and instance_handle are stored in QoS.
+func (endpoint *MatchedEndpoint) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*endpoint = MatchedEndpoint{}
+	for key, value := range fields {
+		var err error
+		switch key {
+		case "name":
+			err = json.Unmarshal(value, &endpoint.Name)
+		case "guid":
+			err = json.Unmarshal(value, &endpoint.GUID)
+		case "instance_handle":
+			err = json.Unmarshal(value, &endpoint.InstanceHandle)
+		default:
+			var qosValue interface{}
+			err = json.Unmarshal(value, &qosValue)
+			if endpoint.QoS == nil {
+				endpoint.QoS = make(map[string]interface{})
+			}
+			endpoint.QoS[key] = qosValue
+		}
+		if err != nil {
+			return errors.New("JSON Unmarshal failed: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// String returns "Matched" or "Unmatched"
+func (kind MatchEventKind) String() string {
+	switch kind {
+	case Matched:
+		return "Matched"
+	case Unmatched:
+		return "Unmatched"
+	}
+	return "MatchEventKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+/********************
+* Private Functions *
+********************/
+
+// parseMatchedEndpoints converts the JSON list returned by
+// RTI_Connector_get_matched_publications/subscriptions into Go values
+func parseMatchedEndpoints(jsonStr string) ([]MatchedEndpoint, error) {
+	endpoints := []MatchedEndpoint{}
+	if jsonStr == "" {
+		return endpoints, nil
+	}
+
+	err := json.Unmarshal([]byte(jsonStr), &endpoints)
+	if err != nil {
+		return nil, errors.New("JSON Unmarshal failed: " + err.Error())
+	}
+
+	return endpoints, nil
+}
+
+// matchedEndpointKey identifies an endpoint across successive listings. The
+// GUID is used when the native layer reports it, the name otherwise.
+func matchedEndpointKey(endpoint MatchedEndpoint) string {
+	if endpoint.GUID != [16]byte{} {
+		return "guid:" + hex.EncodeToString(endpoint.GUID[:])
+	}
+	return "name:" + endpoint.Name
+}
+
+// diffMatchedEndpoints returns the events that turn the previous set of
+// matched endpoints into the current one. Endpoints sharing a key are
+// counted, so two unnamed writers joining produce two Matched events.
+func diffMatchedEndpoints(previous, current []MatchedEndpoint) []MatchEvent {
+	var events []MatchEvent
+
+	remaining := make(map[string][]MatchedEndpoint)
+	for _, endpoint := range previous {
+		key := matchedEndpointKey(endpoint)
+		remaining[key] = append(remaining[key], endpoint)
+	}
+
+	for _, endpoint := range current {
+		key := matchedEndpointKey(endpoint)
+		if len(remaining[key]) > 0 {
+			remaining[key] = remaining[key][1:]
+			continue
+		}
+		events = append(events, MatchEvent{Kind: Matched, Endpoint: endpoint})
+	}
+
+	// Walk previous again to report unmatched endpoints in a stable order
+	for _, endpoint := range previous {
+		key := matchedEndpointKey(endpoint)
+		if len(remaining[key]) > 0 {
+			events = append(events, MatchEvent{Kind: Unmatched, Endpoint: remaining[key][0]})
+			remaining[key] = remaining[key][1:]
+		}
+	}
+
+	return events
+}
+
+// watchMatches starts a goroutine that lists matched endpoints every time
+// wait reports a change (or times out) and emits the difference with the
+// previous listing. The goroutine is a watcher of connector, if not nil, and
+// waits pollWaitMs at a time so as not to hold the mutex of the connector for
+// long. The channel is closed when ctx is done.
+func watchMatches(ctx context.Context, connector *Connector, wait func(timeoutMs int) (int, error), list func() ([]MatchedEndpoint, error)) <-chan MatchEvent {
+	events := make(chan MatchEvent)
+	release := func() {}
+	if connector != nil {
+		release = connector.watch()
+	}
+
+	go func() {
+		defer close(events)
+		defer release()
+
+		var previous []MatchedEndpoint
+		emit := func() error {
+			current, err := list()
+			if err != nil {
+				// Listed again after the next wait
+				return nil
+			}
+			for _, event := range diffMatchedEndpoints(previous, current) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			previous = current
+			return nil
+		}
+		// The endpoints are listed again after timeouts too
+		waitOrTimeout := func(timeoutMs int) error {
+			_, err := wait(timeoutMs)
+			if err == ErrTimeout {
+				return nil
+			}
+			return err
+		}
+
+		if emit() == nil {
+			poll(ctx, waitOrTimeout, emit)
+		}
+	}()
+
+	return events
+}
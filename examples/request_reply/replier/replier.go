@@ -11,21 +11,15 @@
 package main
 
 import (
+	"context"
 	"github.com/rticommunity/rticonnextdds-connector-go"
+	"github.com/rticommunity/rticonnextdds-connector-go/reqrep"
 	"github.com/rticommunity/rticonnextdds-connector-go/types"
 	"log"
 	"path"
 	"runtime"
-	"time"
 )
 
-type Replier struct {
-	connector       *rti.Connector
-	output          *rti.Output
-	input           *rti.Input
-	relatedIdentity string
-}
-
 func main() {
 	// Find the file path to the XML configuration
 	_, filename, _, ok := runtime.Caller(0)
@@ -34,42 +28,28 @@ func main() {
 	}
 	filepath := path.Join(path.Dir(filename), "../RequestReplyExample.xml")
 
-	var replier Replier
-	var err error
-
 	// Create a connector defined in the XML configuration
-	replier.connector, err = rti.NewConnector("MyParticipantLibrary::Replier", filepath)
+	connector, err := rti.NewConnector("MyParticipantLibrary::Replier", filepath)
 	if err != nil {
 		log.Panic(err)
 	}
 	// Delete the connector when this main function returns
-	defer replier.connector.Delete()
+	defer connector.Delete()
 
-	// Get an output from the connector
-	replier.output, err = replier.connector.GetOutput("ReplyPublisher::ReplyWriter")
-	if err != nil {
-		log.Panic(err)
-	}
-	// Get an input from the connector
-	replier.input, err = replier.connector.GetInput("ReplySubscriber::ReplyReader")
+	// Create a replier reading requests and writing the related replies
+	replier, err := reqrep.NewReplier[types.Shape, types.Shape](connector,
+		"ReplySubscriber::ReplyReader", "ReplyPublisher::ReplyWriter")
 	if err != nil {
 		log.Panic(err)
 	}
 
-	// Set values to the instance and write the instance
-	for i := 0; i < 500; i++ {
-		var requestData types.Shape
-		err = replier.receiveRequest(&requestData)
-
-		if err != nil {
-			log.Println(err)
-		} else {
-			log.Println("---Received Request---")
-			log.Printf("color: %s\n", requestData.Color)
-			log.Printf("x: %d\n", requestData.X)
-			log.Printf("y: %d\n", requestData.Y)
-			log.Printf("shapesize: %d\n", requestData.Shapesize)
-		}
+	// Swap x and y of every request received
+	err = replier.Serve(context.Background(), func(ctx context.Context, requestData types.Shape) (types.Shape, error) {
+		log.Println("---Received Request---")
+		log.Printf("color: %s\n", requestData.Color)
+		log.Printf("x: %d\n", requestData.X)
+		log.Printf("y: %d\n", requestData.Y)
+		log.Printf("shapesize: %d\n", requestData.Shapesize)
 
 		var replyData types.Shape
 		replyData.X = requestData.Y
@@ -77,40 +57,15 @@ func main() {
 		replyData.Shapesize = requestData.Shapesize
 		replyData.Color = requestData.Color
 
-		replier.sendReply(&replyData)
+		log.Println("---Sending Reply---")
+		log.Printf("color: %s\n", replyData.Color)
+		log.Printf("x: %d\n", replyData.X)
+		log.Printf("y: %d\n", replyData.Y)
+		log.Printf("shapesize: %d\n", replyData.Shapesize)
 
-		log.Println("---Sent Reply---")
-		log.Printf("color: %s\n", requestData.Color)
-		log.Printf("x: %d\n", requestData.X)
-		log.Printf("y: %d\n", requestData.Y)
-		log.Printf("shapesize: %d\n", requestData.Shapesize)
-
-		time.Sleep(time.Second * 1)
-	}
-}
-
-func (replier *Replier) sendReply(data *types.Shape) {
-	replier.output.Instance.Set(data)
-	params := "{\"related_sample_identity\":" + replier.relatedIdentity + "}"
-	log.Println(params)
-	replier.output.WriteWithParams(params)
-	log.Println("Replied...")
-}
-
-func (replier *Replier) receiveRequest(data *types.Shape) error {
-	err := replier.connector.Wait(-1)
-	if err == nil {
-		replier.input.Take()
-		valid, _ := replier.input.Infos.IsValid(0)
-		if valid {
-			err := replier.input.Samples.Get(0, data)
-			if err != nil {
-				log.Println(err)
-			}
-			log.Println(replier.input.Infos.GetIdentityJSON(0))
-			identity, _ := replier.input.Infos.GetIdentityJSON(0)
-			replier.relatedIdentity = identity
-		}
+		return replyData, nil
+	})
+	if err != nil {
+		log.Panic(err)
 	}
-	return err
 }
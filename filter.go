@@ -0,0 +1,438 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package rti implements functions of RTI Connector for Connext DDS in Go
+package rti
+
+import (
+	"fmt"
+	"iter"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/********
+* Types *
+*********/
+
+// Predicate tells whether a sample is selected by a Query
+type Predicate func(sample SampleView) bool
+
+// Query selects the valid samples of the last Read or Take of an input that
+// match predicates, and the members that SampleView.Get retrieves from them.
+// Queries are built with Input.Where and Input.Select, e.g.:
+//
+//	query := input.Where(rti.MustFilter("x > 10 && color =~ '^B'")).Select("x", "y")
+//	for sample := range query.All() {
+//		err := sample.Get(&shape)
+//	}
+type Query struct {
+	input      *Input
+	predicates []Predicate
+	fields     []string
+}
+
+// sampleGetter is the part of a SampleView read by filter expressions
+type sampleGetter interface {
+	GetString(fieldName string) (string, error)
+	GetFloat64(fieldName string) (float64, error)
+	GetBoolean(fieldName string) (bool, error)
+}
+
+// filterFunc is a compiled filter expression
+type filterFunc func(sample sampleGetter) bool
+
+type filterTokenKind int
+
+const (
+	filterEOF filterTokenKind = iota
+	filterField
+	filterNumber
+	filterString
+	filterBoolean
+	filterOperator
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	// value of string literals, without quotes and escapes
+	value  string
+	offset int
+}
+
+// filterParser is a recursive descent parser of filter expressions
+type filterParser struct {
+	expression string
+	tokens     []filterToken
+	next       int
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// Where returns a query of the valid samples of the input that match
+// predicate
+func (input *Input) Where(predicate Predicate) *Query {
+	return (&Query{input: input}).Where(predicate)
+}
+
+// Select returns a query of the valid samples of the input whose Get only
+// retrieves fields
+func (input *Input) Select(fields ...string) *Query {
+	return (&Query{input: input}).Select(fields...)
+}
+
+// Where returns a query of the samples of query that also match predicate
+func (query *Query) Where(predicate Predicate) *Query {
+	selected := *query
+	selected.predicates = append(slices.Clip(query.predicates), predicate)
+	return &selected
+}
+
+// Select returns a query of the samples of query whose Get only retrieves
+// fields, such as "x" or "position.latitude". Each member is retrieved with
+// GetString, GetFloat64 or GetBoolean according to the kind of the field of
+// the Go struct it is decoded into, rather than as a whole JSON sample.
+func (query *Query) Select(fields ...string) *Query {
+	selected := *query
+	selected.fields = slices.Clone(fields)
+	return &selected
+}
+
+// All returns an iterator over the valid samples of the last Read or Take of
+// the input that match every predicate of the query. Predicates are
+// evaluated before the samples are decoded. If reading the samples fails,
+// the iteration stops and the error is returned by Samples.Err.
+func (query *Query) All() iter.Seq[SampleView] {
+	return func(yield func(SampleView) bool) {
+		if query == nil {
+			return
+		}
+
+		for sample := range query.input.ValidSamples() {
+			if !query.matches(sample) {
+				continue
+			}
+			sample.fields = query.fields
+			if !yield(sample) {
+				return
+			}
+		}
+	}
+}
+
+// Filter is a function to compile a filter expression into a Predicate for
+// Input.Where. Expressions compare members of the sample to literals:
+//
+//	x > 10 && color =~ '^B'
+//	!(shapesize >= 30.5) || (color == "RED" && visible == true)
+//
+// The comparison operators are ==, !=, <, <=, >, >=, =~ (matches a regular
+// expression) and !~ (does not match), combined with &&, || and !. A member
+// alone is true if it is a true boolean. Members are read with GetFloat64,
+// GetString or GetBoolean according to the literal they are compared to. A
+// sample whose member cannot be read does not match.
+func Filter(expression string) (Predicate, error) {
+	filter, err := compileFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(sample SampleView) bool {
+		return filter(sample)
+	}, nil
+}
+
+// MustFilter is like Filter but panics if the expression cannot be compiled
+func MustFilter(expression string) Predicate {
+	predicate, err := Filter(expression)
+	if err != nil {
+		panic(err)
+	}
+	return predicate
+}
+
+/********************
+* Private Functions *
+********************/
+
+// matches is a function to tell whether sample matches every predicate of
+// the query
+func (query *Query) matches(sample SampleView) bool {
+	for _, predicate := range query.predicates {
+		if !predicate(sample) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileFilter is a function to parse a filter expression
+func compileFilter(expression string) (filterFunc, error) {
+	tokens, err := lexFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &filterParser{expression: expression, tokens: tokens}
+	filter, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != filterEOF {
+		return nil, parser.unexpected(token)
+	}
+
+	return filter, nil
+}
+
+// lexFilter is a function to split a filter expression into tokens
+func lexFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isFieldStart(c):
+			start := i
+			for i < len(expression) && (isFieldStart(expression[i]) || isDigit(expression[i]) ||
+				strings.IndexByte(".[]", expression[i]) >= 0) {
+				i++
+			}
+			token := filterToken{kind: filterField, text: expression[start:i], offset: start}
+			if token.text == "true" || token.text == "false" {
+				token.kind = filterBoolean
+			}
+			tokens = append(tokens, token)
+
+		case isDigit(c) || c == '.' || (c == '-' && i+1 < len(expression) && (isDigit(expression[i+1]) || expression[i+1] == '.')):
+			start := i
+			i++
+			for i < len(expression) && (isDigit(expression[i]) || strings.IndexByte(".eE", expression[i]) >= 0 ||
+				(strings.IndexByte("+-", expression[i]) >= 0 && strings.IndexByte("eE", expression[i-1]) >= 0)) {
+				i++
+			}
+			if _, err := strconv.ParseFloat(expression[start:i], 64); err != nil {
+				return nil, fmt.Errorf("filter %q: invalid number %q at offset %d", expression, expression[start:i], start)
+			}
+			tokens = append(tokens, filterToken{kind: filterNumber, text: expression[start:i], offset: start})
+
+		case c == '\'' || c == '"':
+			start := i
+			var value strings.Builder
+			for i++; i < len(expression) && expression[i] != c; i++ {
+				if expression[i] == '\\' && i+1 < len(expression) {
+					i++
+				}
+				value.WriteByte(expression[i])
+			}
+			if i == len(expression) {
+				return nil, fmt.Errorf("filter %q: unterminated string at offset %d", expression, start)
+			}
+			i++
+			tokens = append(tokens, filterToken{kind: filterString, text: expression[start:i], value: value.String(), offset: start})
+
+		default:
+			operator := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(expression[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("filter %q: unexpected %q at offset %d", expression, c, i)
+			}
+			tokens = append(tokens, filterToken{kind: filterOperator, text: operator, offset: i})
+			i += len(operator)
+		}
+	}
+
+	return append(tokens, filterToken{kind: filterEOF, offset: len(expression)}), nil
+}
+
+func isFieldStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (parser *filterParser) peek() filterToken {
+	return parser.tokens[parser.next]
+}
+
+// accept is a function to consume the next token if it is the operator
+func (parser *filterParser) accept(operator string) bool {
+	if token := parser.peek(); token.kind == filterOperator && token.text == operator {
+		parser.next++
+		return true
+	}
+	return false
+}
+
+func (parser *filterParser) unexpected(token filterToken) error {
+	if token.kind == filterEOF {
+		return fmt.Errorf("filter %q: unexpected end of expression", parser.expression)
+	}
+	return fmt.Errorf("filter %q: unexpected %q at offset %d", parser.expression, token.text, token.offset)
+}
+
+// parseOr is a function to parse: and { "||" and }
+func (parser *filterParser) parseOr() (filterFunc, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for parser.accept("||") {
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = func(either, or filterFunc) filterFunc {
+			return func(sample sampleGetter) bool { return either(sample) || or(sample) }
+		}(left, right)
+	}
+	return left, nil
+}
+
+// parseAnd is a function to parse: unary { "&&" unary }
+func (parser *filterParser) parseAnd() (filterFunc, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for parser.accept("&&") {
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = func(both, and filterFunc) filterFunc {
+			return func(sample sampleGetter) bool { return both(sample) && and(sample) }
+		}(left, right)
+	}
+	return left, nil
+}
+
+// parseUnary is a function to parse: "!" unary | "(" or ")" | comparison
+func (parser *filterParser) parseUnary() (filterFunc, error) {
+	switch {
+	case parser.accept("!"):
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(sample sampleGetter) bool { return !operand(sample) }, nil
+
+	case parser.accept("("):
+		inner, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.accept(")") {
+			return nil, parser.unexpected(parser.peek())
+		}
+		return inner, nil
+	}
+
+	return parser.parseComparison()
+}
+
+// parseComparison is a function to parse: field [ operator literal ]
+func (parser *filterParser) parseComparison() (filterFunc, error) {
+	field := parser.peek()
+	if field.kind != filterField {
+		return nil, parser.unexpected(field)
+	}
+	parser.next++
+
+	operator := parser.peek()
+	if operator.kind != filterOperator || !slices.Contains([]string{"==", "!=", "<", "<=", ">", ">=", "=~", "!~"}, operator.text) {
+		// A member alone is a boolean
+		return func(sample sampleGetter) bool {
+			value, err := sample.GetBoolean(field.text)
+			return err == nil && value
+		}, nil
+	}
+	parser.next++
+
+	literal := parser.peek()
+	parser.next++
+	name, op := field.text, operator.text
+	switch literal.kind {
+	case filterNumber:
+		if op == "=~" || op == "!~" {
+			break
+		}
+		number, _ := strconv.ParseFloat(literal.text, 64)
+		return func(sample sampleGetter) bool {
+			value, err := sample.GetFloat64(name)
+			return err == nil && compare(op, value, number)
+		}, nil
+
+	case filterString:
+		if op == "=~" || op == "!~" {
+			pattern, err := regexp.Compile(literal.value)
+			if err != nil {
+				return nil, fmt.Errorf("filter %q: %v", parser.expression, err)
+			}
+			return func(sample sampleGetter) bool {
+				value, err := sample.GetString(name)
+				return err == nil && pattern.MatchString(value) == (op == "=~")
+			}, nil
+		}
+		text := literal.value
+		return func(sample sampleGetter) bool {
+			value, err := sample.GetString(name)
+			return err == nil && compare(op, value, text)
+		}, nil
+
+	case filterBoolean:
+		if op != "==" && op != "!=" {
+			break
+		}
+		boolean := literal.text == "true"
+		return func(sample sampleGetter) bool {
+			value, err := sample.GetBoolean(name)
+			return err == nil && (value == boolean) == (op == "==")
+		}, nil
+
+	default:
+		return nil, parser.unexpected(literal)
+	}
+
+	return nil, fmt.Errorf("filter %q: operator %s cannot compare %s at offset %d", parser.expression, op, literal.text, literal.offset)
+}
+
+// compare is a function to apply a comparison operator to two values
+func compare[T float64 | string](op string, value, literal T) bool {
+	switch op {
+	case "==":
+		return value == literal
+	case "!=":
+		return value != literal
+	case "<":
+		return value < literal
+	case "<=":
+		return value <= literal
+	case ">":
+		return value > literal
+	case ">=":
+		return value >= literal
+	}
+	return false
+}
@@ -1,6 +1,6 @@
 module github.com/rticommunity/rticonnextdds-connector-go
 
-go 1.21
+go 1.23
 
 require github.com/stretchr/testify v1.11.1
 
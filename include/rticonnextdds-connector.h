@@ -199,5 +199,9 @@ int RTI_Connector_get_native_instance(
 
 void RTI_Connector_free_string(char *str);
 
+int RTI_Connector_get_build_versions(
+	char **core_version,
+	char **connector_version);
+
 int RTI_Connector_set_max_objects_per_thread(
 	int value);
@@ -17,6 +17,7 @@ import "C"
 import (
 	"encoding/json"
 	"errors"
+	"iter"
 	"strconv"
 	"unsafe"
 )
@@ -28,9 +29,26 @@ import (
 // Infos is a sequence of info samples used by an input to read DDS meta data
 type Infos struct {
 	input *Input
+	err   error // error that stopped the last iteration
 }
 
-// Identity is the structure for identifying
+// SampleInfo holds the meta data of one sample. RelatedIdentity is zero
+// when the sample is not related to another one.
+type SampleInfo struct {
+	Valid              bool
+	SourceTimestamp    int64
+	ReceptionTimestamp int64
+	Identity           Identity
+	RelatedIdentity    Identity
+	ViewState          string
+	InstanceState      string
+	SampleState        string
+}
+
+// Identity is the structure for identifying a sample. It marshals into the
+// JSON format used by WriteWithParams for "identity" and
+// "related_sample_identity": the GUID as a list of 16 bytes and the sequence
+// number as an integer.
 type Identity struct {
 	WriterGUID     [16]byte `json:"writer_guid"`
 	SequenceNumber int      `json:"sequence_number"`
@@ -53,6 +71,9 @@ func (infos *Infos) IsValid(index int) (bool, error) {
 	defer C.free(unsafe.Pointer(memberNameCStr))
 	var retVal C.int
 
+	infos.input.connector.mutex.Lock()
+	defer infos.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_boolean_from_infos(unsafe.Pointer(infos.input.connector.native), &retVal, infos.input.nameCStr, C.int(index+1), memberNameCStr))
 	err := checkRetcode(retcode)
 
@@ -180,17 +201,94 @@ func (infos *Infos) GetSampleState(index int) (string, error) {
 // GetLength is a function to return the length of the
 func (infos *Infos) GetLength() (int, error) {
 	var retVal C.double
+
+	infos.input.connector.mutex.Lock()
+	defer infos.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_sample_count(unsafe.Pointer(infos.input.connector.native), infos.input.nameCStr, &retVal))
 	err := checkRetcode(retcode)
 	return int(retVal), err
 }
 
+// GetInfo is a function to get all the meta data of a sample at once
+func (infos *Infos) GetInfo(index int) (SampleInfo, error) {
+	var info SampleInfo
+	var err error
+
+	if info.Valid, err = infos.IsValid(index); err != nil {
+		return info, err
+	}
+	if info.SourceTimestamp, err = infos.GetSourceTimestamp(index); err != nil {
+		return info, err
+	}
+	if info.ReceptionTimestamp, err = infos.GetReceptionTimestamp(index); err != nil {
+		return info, err
+	}
+	if info.Identity, err = infos.GetIdentity(index); err != nil {
+		return info, err
+	}
+	if info.ViewState, err = infos.GetViewState(index); err != nil {
+		return info, err
+	}
+	if info.InstanceState, err = infos.GetInstanceState(index); err != nil {
+		return info, err
+	}
+	if info.SampleState, err = infos.GetSampleState(index); err != nil {
+		return info, err
+	}
+	// Samples that are not related to another one may have no related identity
+	info.RelatedIdentity, _ = infos.GetRelatedIdentity(index)
+
+	return info, nil
+}
+
+// All returns an iterator over the index and the meta data of every sample of
+// the last Read or Take. If reading the meta data fails, the iteration stops
+// and the error is returned by Err.
+func (infos *Infos) All() iter.Seq2[int, SampleInfo] {
+	return func(yield func(int, SampleInfo) bool) {
+		if infos == nil || infos.input == nil || infos.input.connector == nil {
+			return
+		}
+		infos.err = nil
+
+		length, err := infos.GetLength()
+		if err != nil {
+			infos.err = err
+			return
+		}
+
+		for i := 0; i < length; i++ {
+			info, err := infos.GetInfo(i)
+			if err != nil {
+				infos.err = err
+				return
+			}
+			if !yield(i, info) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error that stopped the last iteration over All, or nil if
+// it completed
+func (infos *Infos) Err() error {
+	if infos == nil || infos.input == nil || infos.input.connector == nil {
+		return errors.New("infos, input, or connector is null")
+	}
+	return infos.err
+}
+
 func (infos *Infos) getJSONMember(index int, memberName string) (string, error) {
 	memberNameCStr := C.CString(memberName)
 	defer C.free(unsafe.Pointer(memberNameCStr))
 
 	var retValCStr *C.char
 
+	infos.input.connector.mutex.Lock()
+	defer infos.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_json_from_infos(unsafe.Pointer(infos.input.connector.native), infos.input.nameCStr, C.int(index+1), memberNameCStr, &retValCStr))
 	err := checkRetcode(retcode)
 	if err != nil {
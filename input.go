@@ -15,10 +15,23 @@ package rti
 // #include <stdlib.h>
 import "C"
 import (
+	"context"
 	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
 	"unsafe"
 )
 
+// pollWaitMs bounds how long the goroutines of MatchEvents, InstanceEvents
+// and LastValueCache.Run block in the native layer. They hold the mutex of the
+// connector meanwhile, so it also bounds how long they delay other calls.
+const pollWaitMs = 10
+
+// pollMs delays the retries of failed waits
+const pollMs = 100
+
 /********
 * Types *
 *********/
@@ -31,6 +44,8 @@ type Input struct {
 	nameCStr  *C.char
 	Samples   *Samples
 	Infos     *Infos
+	// samples taken by ReadWith, TakeWith and TakeNextInstance
+	queue *sampleQueue
 }
 
 /*******************
@@ -45,6 +60,9 @@ func (input *Input) Read() error {
 		return errors.New("input is null")
 	}
 
+	input.connector.mutex.Lock()
+	defer input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_read(unsafe.Pointer(input.connector.native), input.nameCStr))
 	return checkRetcode(retcode)
 }
@@ -57,10 +75,27 @@ func (input *Input) Take() error {
 		return errors.New("input is null")
 	}
 
+	input.connector.mutex.Lock()
+	defer input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_take(unsafe.Pointer(input.connector.native), input.nameCStr))
 	return checkRetcode(retcode)
 }
 
+// Wait blocks until data is available on this input. Unlike Connector.Wait,
+// data arriving on other inputs of the same Connector does not wake it up.
+func (input *Input) Wait(timeoutMs int) error {
+	if input == nil {
+		return errors.New("input is null")
+	}
+
+	input.connector.mutex.Lock()
+	defer input.connector.mutex.Unlock()
+
+	retcode := int(C.RTI_Connector_wait_for_data_on_reader(input.native, C.int(timeoutMs)))
+	return checkRetcode(retcode)
+}
+
 // Waits until this input matches or unmatches a compatible DDS subscription.
 // If the operation times out, it will raise :class:`TimeoutError`.
 // Parameters:
@@ -75,6 +110,9 @@ func (input *Input) WaitForPublications(timeoutMs int) (int, error) {
 
 	var currentCountChange C.int
 
+	input.connector.mutex.Lock()
+	defer input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_wait_for_matched_publication(unsafe.Pointer(input.native), C.int(timeoutMs), &currentCountChange))
 	return int(currentCountChange), checkRetcode(retcode)
 }
@@ -89,6 +127,8 @@ func (input *Input) WaitForPublications(timeoutMs int) (int, error) {
 
 // Note that Connector Outputs are automatically assigned a name from the
 // *data_writer name* in the XML configuration.
+//
+// See MatchedPublications for the same information decoded into Go values.
 func (input *Input) GetMatchedPublications() (string, error) {
 	if input == nil {
 		return "", errors.New("input is null")
@@ -96,6 +136,9 @@ func (input *Input) GetMatchedPublications() (string, error) {
 
 	var jsonCStr *C.char
 
+	input.connector.mutex.Lock()
+	defer input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_matched_publications(unsafe.Pointer(input.native), &jsonCStr))
 	err := checkRetcode(retcode)
 	if err != nil {
@@ -107,3 +150,167 @@ func (input *Input) GetMatchedPublications() (string, error) {
 
 	return jsonGoStr, nil
 }
+
+// MatchedPublications returns the publications matched with this Input as a
+// slice of MatchedEndpoint values
+func (input *Input) MatchedPublications() ([]MatchedEndpoint, error) {
+	jsonStr, err := input.GetMatchedPublications()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMatchedEndpoints(jsonStr)
+}
+
+// MatchedPublicationCount returns the number of publications currently
+// matched with this Input
+func (input *Input) MatchedPublicationCount() (int, error) {
+	endpoints, err := input.MatchedPublications()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(endpoints), nil
+}
+
+// SetPartitions is a function to move the subscriber of the input to
+// partitions, e.g. to follow the tenant assignment of a gateway. An empty
+// list moves it to the default partition.
+//
+// The Connector API cannot change the QoS of existing entities, so the
+// participant of the connector is created again, with the XML configuration
+// and options given to NewConnector, and its inputs and outputs are pointed
+// to the new readers and writers. Samples not taken yet are lost, the fields
+// set in the Instance of every output are reset and must be set again before
+// the next Write, and matches are discovered again. The samples already
+// taken into the queue of ReadWith and TakeWith are kept. SetPartitions must
+// not be called
+// concurrently with other functions of the connector, its inputs or outputs,
+// and fails with ErrConnectorInUse while goroutines started by MatchEvents,
+// InstanceEvents, LastValueCache.Run or Connector.Watch use them.
+func (input *Input) SetPartitions(partitions []string) error {
+	if input == nil {
+		return errors.New("input is null")
+	}
+
+	group, _, _ := strings.Cut(input.name, "::")
+	return input.connector.setPartitions("subscriber:"+group, partitions)
+}
+
+// Partitions is a function to return the partitions of the subscriber of the
+// input, as set in the XML configuration, by QoS overrides or by
+// SetPartitions. It is empty in the default partition.
+func (input *Input) Partitions() ([]string, error) {
+	if input == nil {
+		return nil, errors.New("input is null")
+	}
+
+	document, err := readConfigURL(input.connector.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	group, _, _ := strings.Cut(input.name, "::")
+	return groupPartitions(document, input.connector.configName, group, true)
+}
+
+// SetFilterParameters is a function to change the parameters of the content
+// filter of the input, set by Connector.NewFilteredInput or
+// WithContentFilter, e.g. to raise a threshold at runtime. The participant
+// of the connector is created again, as by SetPartitions: every input of the
+// connector loses the samples not taken yet, every output the fields set in
+// its Instance, and ErrConnectorInUse is returned while watchers use the
+// connector.
+func (input *Input) SetFilterParameters(params []string) error {
+	if input == nil {
+		return errors.New("input is null")
+	}
+
+	filter, ok := input.connector.options.filters[input.name]
+	if !ok {
+		return fmt.Errorf("input %q has no content filter set by NewFilteredInput or WithContentFilter", input.name)
+	}
+	filter.parameters = params
+	return input.connector.setContentFilter(input.name, filter)
+}
+
+// MatchEvents returns a channel reporting publications that match or unmatch
+// this Input. Publications already matched when it is called are reported as
+// Matched first. The channel is closed when ctx is done.
+//
+// The events are produced by a goroutine calling into the native layer, so
+// ctx must be cancelled and the channel drained before the Connector is
+// deleted. Its calls are serialized with the other calls into the connector,
+// which may keep being used meanwhile. See the threading model in the README.
+func (input *Input) MatchEvents(ctx context.Context) <-chan MatchEvent {
+	var connector *Connector
+	if input != nil {
+		connector = input.connector
+	}
+	return watchMatches(ctx, connector, input.WaitForPublications, input.MatchedPublications)
+}
+
+// ValidSamples returns an iterator over the samples of the last Read or Take
+// that contain valid data, skipping for example the samples that only notify
+// a dispose. If reading the samples fails, the iteration stops and the error
+// is returned by Samples.Err.
+func (input *Input) ValidSamples() iter.Seq[SampleView] {
+	return func(yield func(SampleView) bool) {
+		if input == nil {
+			return
+		}
+
+		for sample := range input.Samples.All() {
+			valid, err := input.Infos.IsValid(sample.Index())
+			if err != nil {
+				input.Samples.err = err
+				return
+			}
+			if valid && !yield(sample) {
+				return
+			}
+		}
+	}
+}
+
+/********************
+* Private Functions *
+********************/
+
+// poll is a function to call wait with pollWaitMs until ctx is done, and onData every time
+// wait returns without error. Failures of wait other than a timeout are
+// retried after pollMs. poll returns the error of ctx once it is done, or the
+// first error of onData.
+func poll(ctx context.Context, wait func(timeoutMs int) error, onData func() error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := wait(pollWaitMs)
+		if err == ErrTimeout {
+			continue
+		}
+		if err != nil {
+			// Avoid spinning when the native layer keeps failing
+			select {
+			case <-time.After(pollMs * time.Millisecond):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		if err := onData(); err != nil {
+			return err
+		}
+	}
+}
+
+// typeKeyMembers is a function to return the key members of the type of the
+// input, read from the XML configuration of its connector
+func (input *Input) typeKeyMembers() ([]xmlMember, error) {
+	document, err := readConfigURL(input.connector.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return keyMembers(document, input.connector.configName, input.name)
+}
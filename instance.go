@@ -38,6 +38,9 @@ func (instance *Instance) SetUint8(fieldName string, value uint8) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -47,6 +50,9 @@ func (instance *Instance) SetUint16(fieldName string, value uint16) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -56,6 +62,9 @@ func (instance *Instance) SetUint32(fieldName string, value uint32) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -65,6 +74,9 @@ func (instance *Instance) SetUint64(fieldName string, value uint64) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -74,6 +86,9 @@ func (instance *Instance) SetInt8(fieldName string, value int8) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -83,6 +98,9 @@ func (instance *Instance) SetInt16(fieldName string, value int16) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -92,6 +110,9 @@ func (instance *Instance) SetInt32(fieldName string, value int32) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -101,6 +122,9 @@ func (instance *Instance) SetInt64(fieldName string, value int64) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -110,6 +134,9 @@ func (instance *Instance) SetUint(fieldName string, value uint) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -119,6 +146,9 @@ func (instance *Instance) SetInt(fieldName string, value int) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -128,6 +158,9 @@ func (instance *Instance) SetFloat32(fieldName string, value float32) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -137,6 +170,9 @@ func (instance *Instance) SetFloat64(fieldName string, value float64) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -156,6 +192,9 @@ func (instance *Instance) SetString(fieldName, value string) error {
 	valueCStr := C.CString(value)
 	defer C.free(unsafe.Pointer(valueCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_string_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, valueCStr))
 	return checkRetcode(retcode)
 }
@@ -165,6 +204,9 @@ func (instance *Instance) SetByte(fieldName string, value byte) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -174,6 +216,9 @@ func (instance *Instance) SetRune(fieldName string, value rune) error {
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_number_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.double(value)))
 	return checkRetcode(retcode)
 }
@@ -187,6 +232,10 @@ func (instance *Instance) SetBoolean(fieldName string, value bool) error {
 	if value {
 		intValue = 1
 	}
+
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_boolean_into_samples(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, fieldNameCStr, C.int(intValue)))
 	return checkRetcode(retcode)
 }
@@ -196,6 +245,9 @@ func (instance *Instance) SetJSON(blob []byte) error {
 	jsonCStr := C.CString(string(blob))
 	defer C.free(unsafe.Pointer(jsonCStr))
 
+	instance.output.connector.mutex.Lock()
+	defer instance.output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_set_json_instance(unsafe.Pointer(instance.output.connector.native), instance.output.nameCStr, jsonCStr))
 	return checkRetcode(retcode)
 }
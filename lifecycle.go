@@ -0,0 +1,234 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package rti implements functions of RTI Connector for Connext DDS in Go
+package rti
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+)
+
+/********
+* Types *
+*********/
+
+// InstanceEventKind tells how an instance of an input changed
+type InstanceEventKind int
+
+const (
+	// InstanceNew is reported for the first sample of an instance, or of an
+	// instance written again after it was disposed or lost its writers
+	InstanceNew InstanceEventKind = iota
+	// InstanceUpdated is reported for the other samples of an instance
+	InstanceUpdated
+	// InstanceDisposed is reported when an instance is disposed
+	InstanceDisposed
+	// InstanceNoWriters is reported when no writer writes an instance anymore
+	InstanceNoWriters
+)
+
+// InstanceEvent is a change of an instance emitted by Input.InstanceEvents.
+// Key holds the values of the key members of the instance, by member name.
+type InstanceEvent struct {
+	Kind InstanceEventKind
+	Key  map[string]interface{}
+	Info SampleInfo
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// InstanceEvents returns a channel reporting the instances of this Input
+// that appear, are updated, are disposed or lose all their writers. For
+// every Take, the New and Updated events of its valid samples are reported
+// first, then the Disposed and NoWriters events of its instances. Only the
+// key members of the samples are read, so the samples are not decoded.
+//
+// The events are produced by a goroutine taking the samples of the Input,
+// which must therefore not be read otherwise. Its calls are serialized with
+// the other calls into the connector, whose other inputs and outputs may keep
+// being used meanwhile. The channel is closed when ctx is done. An error is returned, and no goroutine started, if the type of
+// the Input cannot be found in the XML configuration.
+// As for MatchEvents, ctx must be cancelled and the channel drained before
+// the Connector is deleted.
+func (input *Input) InstanceEvents(ctx context.Context) (<-chan InstanceEvent, error) {
+	if input == nil {
+		return nil, errors.New("input is null")
+	}
+
+	keys, err := input.typeKeyMembers()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan InstanceEvent)
+	release := input.connector.watch()
+
+	go func() {
+		defer close(events)
+		defer release()
+
+		// Failures to take or decode the samples are retried like failures
+		// of the wait
+		var taken []InstanceEvent
+		wait := func(timeoutMs int) error {
+			err := input.Wait(timeoutMs)
+			if err == nil {
+				err = input.Take()
+			}
+			if err == nil {
+				taken, err = input.instanceEvents(keys)
+			}
+			return err
+		}
+		poll(ctx, wait, func() error {
+			for _, event := range taken {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}()
+
+	return events, nil
+}
+
+// String returns "New", "Updated", "Disposed" or "NoWriters"
+func (kind InstanceEventKind) String() string {
+	switch kind {
+	case InstanceNew:
+		return "New"
+	case InstanceUpdated:
+		return "Updated"
+	case InstanceDisposed:
+		return "Disposed"
+	case InstanceNoWriters:
+		return "NoWriters"
+	}
+	return "InstanceEventKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+/********************
+* Private Functions *
+********************/
+
+// instanceEvents is a function to return the events of the samples of the
+// last Take of the input. The state of an instance may be conveyed by its
+// last valid sample rather than by a sample without valid data, so it is
+// reported once for each instance of the Take.
+func (input *Input) instanceEvents(keys []xmlMember) ([]InstanceEvent, error) {
+	length, err := input.Samples.GetLength()
+	if err != nil {
+		return nil, err
+	}
+
+	type instance struct {
+		last    InstanceEvent
+		updated bool
+	}
+	instances := make(map[string]*instance)
+	// instances in the order of their last sample
+	var order []string
+
+	var events []InstanceEvent
+	for i := 0; i < length; i++ {
+		info, err := input.Infos.GetInfo(i)
+		if err != nil {
+			return nil, err
+		}
+		key, err := input.keyValues(i, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]interface{}, len(keys))
+		for j, member := range keys {
+			values[j] = key[member.Name]
+		}
+		id := cacheKey(values)
+		current := instances[id]
+		if current == nil {
+			current = new(instance)
+			instances[id] = current
+		}
+		current.last = InstanceEvent{Key: key, Info: info}
+		order = append(slices.DeleteFunc(order, func(other string) bool { return other == id }), id)
+
+		if info.Valid {
+			event := InstanceEvent{Kind: InstanceUpdated, Key: key, Info: info}
+			if info.ViewState == "NEW" && !current.updated {
+				event.Kind = InstanceNew
+			}
+			current.updated = true
+			events = append(events, event)
+		}
+	}
+
+	for _, id := range order {
+		event := instances[id].last
+		switch event.Info.InstanceState {
+		case "NOT_ALIVE_DISPOSED":
+			event.Kind = InstanceDisposed
+		case "NOT_ALIVE_NO_WRITERS":
+			event.Kind = InstanceNoWriters
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// keyValues is a function to return the values of the key members of the
+// sample at index, read one by one according to their XML type
+func (input *Input) keyValues(index int, keys []xmlMember) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(keys))
+	// the whole sample, for key members that are not strings, numbers or
+	// booleans
+	var sample map[string]interface{}
+
+	for _, key := range keys {
+		var value interface{}
+		var err error
+		switch key.Type {
+		case "string", "wstring":
+			value, err = input.Samples.GetString(index, key.Name)
+		case "boolean":
+			value, err = input.Samples.GetBoolean(index, key.Name)
+		case "octet", "byte", "int8", "uint8", "int16", "uint16", "int32", "uint32", "int64", "uint64",
+			"short", "unsignedShort", "long", "unsignedLong", "longLong", "unsignedLongLong",
+			"float32", "float64", "float", "double":
+			value, err = input.Samples.GetFloat64(index, key.Name)
+		default:
+			if sample == nil {
+				var jsonData []byte
+				if jsonData, err = input.Samples.GetJSON(index); err == nil {
+					err = json.Unmarshal(jsonData, &sample)
+				}
+			}
+			value = sample[key.Name]
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key.Name] = value
+	}
+
+	return values, nil
+}
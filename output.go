@@ -16,7 +16,11 @@ package rti
 import "C"
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 	"unsafe"
 )
 
@@ -33,6 +37,17 @@ type Output struct {
 	Instance  *Instance
 }
 
+// WriteParams are the parameters of WriteWithParams as Go values, written by
+// WriteWith. Empty and nil parameters are not set.
+type WriteParams struct {
+	// Action is one of "write" (default), "dispose" or "unregister"
+	Action string `json:"action,omitempty"`
+	// SourceTimestamp is in nanoseconds since the Unix epoch
+	SourceTimestamp       *int64    `json:"source_timestamp,omitempty"`
+	Identity              *Identity `json:"identity,omitempty"`
+	RelatedSampleIdentity *Identity `json:"related_sample_identity,omitempty"`
+}
+
 /*******************
 * Public Functions *
 *******************/
@@ -43,6 +58,9 @@ func (output *Output) Write() error {
 		return errors.New("output is null")
 	}
 
+	output.connector.mutex.Lock()
+	defer output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_write(unsafe.Pointer(output.connector.native), output.nameCStr, nil))
 	return checkRetcode(retcode)
 }
@@ -54,10 +72,12 @@ func (output *Output) Write() error {
 // identity: A dictionary containing the keys "writer_guid" (a list of 16 bytes) and "sequence_number" (an integer) that uniquely identifies this sample.
 // related_sample_identity: Used for request-reply communications. It has the same format as "identity"
 // For example::
-// output.Write(
+// output.WriteWithParams(
+//
+//	`{"identity": {"writer_guid": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16], "sequence_number": 1},
+//	  "source_timestamp": 1000000000}`)
 //
-//	  identity={"writer_guid":[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15], "sequence_number":1},
-//		 timestamp=1000000000)
+// WriteWith, WriteAt and WriteWithIdentity build the parameters from Go values.
 func (output *Output) WriteWithParams(jsonStr string) error {
 	if output == nil {
 		return errors.New("output is null")
@@ -66,16 +86,51 @@ func (output *Output) WriteWithParams(jsonStr string) error {
 	jsonCStr := C.CString(jsonStr)
 	defer C.free(unsafe.Pointer(jsonCStr))
 
+	output.connector.mutex.Lock()
+	defer output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_write(unsafe.Pointer(output.connector.native), output.nameCStr, jsonCStr))
 	return checkRetcode(retcode)
 }
 
+// WriteAt is a function to write a DDS data instance with t as its source timestamp
+func (output *Output) WriteAt(t time.Time) error {
+	if t.Before(time.Unix(0, 0)) {
+		return errors.New("source timestamp cannot be before the Unix epoch")
+	}
+
+	sourceTimestamp := t.UnixNano()
+	return output.WriteWith(WriteParams{SourceTimestamp: &sourceTimestamp})
+}
+
+// WriteWithIdentity is a function to write a DDS data instance identified by id
+// instead of the identity assigned by the DataWriter
+func (output *Output) WriteWithIdentity(id Identity) error {
+	return output.WriteWith(WriteParams{Identity: &id})
+}
+
+// WriteWith is a function to write a DDS data instance with params, e.g. a
+// reply related to the request with the identity id:
+//
+//	output.WriteWith(rti.WriteParams{RelatedSampleIdentity: &id})
+func (output *Output) WriteWith(params WriteParams) error {
+	jsonData, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return output.WriteWithParams(string(jsonData))
+}
+
 // ClearMembers is a function to initialize a DDS data instance in an output
 func (output *Output) ClearMembers() error {
 	if output == nil {
 		return errors.New("output is null")
 	}
 
+	output.connector.mutex.Lock()
+	defer output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_clear(unsafe.Pointer(output.connector.native), output.nameCStr))
 	return checkRetcode(retcode)
 }
@@ -95,6 +150,9 @@ func (output *Output) WaitForSubscriptions(timeoutMs int) (int, error) {
 
 	var currentCountChange C.int
 
+	output.connector.mutex.Lock()
+	defer output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_wait_for_matched_subscription(unsafe.Pointer(output.native), C.int(timeoutMs), &currentCountChange))
 	return int(currentCountChange), checkRetcode(retcode)
 }
@@ -110,6 +168,8 @@ func (output *Output) WaitForSubscriptions(timeoutMs int) (int, error) {
 
 // Note that Connector Inputs are automatically assigned a name from the
 // *data_reader name* in the XML configuration.
+//
+// See MatchedSubscriptions for the same information decoded into Go values.
 func (output *Output) GetMatchedSubscriptions() (string, error) {
 	if output == nil {
 		return "", errors.New("output is null")
@@ -117,6 +177,9 @@ func (output *Output) GetMatchedSubscriptions() (string, error) {
 
 	var jsonCStr *C.char
 
+	output.connector.mutex.Lock()
+	defer output.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_matched_subscriptions(unsafe.Pointer(output.native), &jsonCStr))
 	err := checkRetcode(retcode)
 	if err != nil {
@@ -128,3 +191,81 @@ func (output *Output) GetMatchedSubscriptions() (string, error) {
 
 	return jsonGoStr, nil
 }
+
+// MatchedSubscriptions returns the subscriptions matched with this Output as
+// a slice of MatchedEndpoint values
+func (output *Output) MatchedSubscriptions() ([]MatchedEndpoint, error) {
+	jsonStr, err := output.GetMatchedSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMatchedEndpoints(jsonStr)
+}
+
+// MatchedSubscriptionCount returns the number of subscriptions currently
+// matched with this Output
+func (output *Output) MatchedSubscriptionCount() (int, error) {
+	endpoints, err := output.MatchedSubscriptions()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(endpoints), nil
+}
+
+// SetPartitions is a function to move the publisher of the output to
+// partitions, e.g. to follow the tenant assignment of a gateway. An empty
+// list moves it to the default partition.
+//
+// The Connector API cannot change the QoS of existing entities, so the
+// participant of the connector is created again, with the XML configuration
+// and options given to NewConnector, and its inputs and outputs are pointed
+// to the new readers and writers. Samples not taken yet are lost, the fields
+// set in the Instance of every output are reset and must be set again before
+// the next Write, and matches are discovered again. The samples already
+// taken into the queue of ReadWith and TakeWith are kept. SetPartitions must
+// not be called
+// concurrently with other functions of the connector, its inputs or outputs,
+// and fails with ErrConnectorInUse while goroutines started by MatchEvents,
+// InstanceEvents, LastValueCache.Run or Connector.Watch use them.
+func (output *Output) SetPartitions(partitions []string) error {
+	if output == nil {
+		return errors.New("output is null")
+	}
+
+	group, _, _ := strings.Cut(output.name, "::")
+	return output.connector.setPartitions("publisher:"+group, partitions)
+}
+
+// Partitions is a function to return the partitions of the publisher of the
+// output, as set in the XML configuration, by QoS overrides or by
+// SetPartitions. It is empty in the default partition.
+func (output *Output) Partitions() ([]string, error) {
+	if output == nil {
+		return nil, errors.New("output is null")
+	}
+
+	document, err := readConfigURL(output.connector.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	group, _, _ := strings.Cut(output.name, "::")
+	return groupPartitions(document, output.connector.configName, group, false)
+}
+
+// MatchEvents returns a channel reporting subscriptions that match or unmatch
+// this Output. Subscriptions already matched when it is called are reported
+// as Matched first. The channel is closed when ctx is done.
+//
+// The events are produced by a goroutine calling into the native layer, so
+// ctx must be cancelled and the channel drained before the Connector is
+// deleted. Its calls are serialized with the other calls into the connector,
+// which may keep being used meanwhile. See the threading model in the README.
+func (output *Output) MatchEvents(ctx context.Context) <-chan MatchEvent {
+	var connector *Connector
+	if output != nil {
+		connector = output.connector
+	}
+	return watchMatches(ctx, connector, output.WaitForSubscriptions, output.MatchedSubscriptions)
+}
@@ -0,0 +1,106 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package qos provides the QoS policies that rti.WithReaderQoS,
+// rti.WithWriterQoS and rti.WithParticipantQoS apply on top of the XML
+// profiles of a participant, e.g.:
+//
+//	connector, err := rti.NewConnector(configName, url,
+//		rti.WithReaderQoS("MySubscriber::MyReader", qos.History(qos.KeepLast(10)), qos.Reliability(qos.BestEffort)))
+package qos
+
+import (
+	"time"
+
+	"github.com/rticommunity/rticonnextdds-connector-go/config"
+)
+
+/********
+* Types *
+*********/
+
+// Policy sets a QoS policy of an entity
+type Policy func(q *config.QoS)
+
+const (
+	// BestEffort does not repair lost samples
+	BestEffort = config.BestEffortReliability
+	// Reliable repairs lost samples
+	Reliable = config.ReliableReliability
+
+	// Volatile does not deliver samples to late joiners
+	Volatile = config.VolatileDurability
+	// TransientLocal delivers the samples in the writer history to late joiners
+	TransientLocal = config.TransientLocalDurability
+	// Transient delivers samples kept by a persistence service while it runs
+	Transient = config.TransientDurability
+	// Persistent delivers samples kept on disk by a persistence service
+	Persistent = config.PersistentDurability
+)
+
+/*******************
+* Public Functions *
+*******************/
+
+// Reliability is a function to set the reliability kind
+func Reliability(kind config.ReliabilityKind) Policy {
+	return func(q *config.QoS) {
+		q.Reliability = &config.Reliability{Kind: kind}
+	}
+}
+
+// History is a function to set the history, KeepLast or KeepAll
+func History(history config.History) Policy {
+	return func(q *config.QoS) {
+		q.History = &history
+	}
+}
+
+// KeepLast is a function to keep the last depth samples of each instance
+func KeepLast(depth int) config.History {
+	return config.History{Kind: config.KeepLastHistory, Depth: depth}
+}
+
+// KeepAll is a function to keep every sample until it is acknowledged or taken
+func KeepAll() config.History {
+	return config.History{Kind: config.KeepAllHistory}
+}
+
+// Durability is a function to set the durability kind
+func Durability(kind config.DurabilityKind) Policy {
+	return func(q *config.QoS) {
+		q.Durability = &config.Durability{Kind: kind}
+	}
+}
+
+// Deadline is a function to set the maximum period between the samples of an
+// instance
+func Deadline(period time.Duration) Policy {
+	return func(q *config.QoS) {
+		q.Deadline = &config.Deadline{Period: config.NewDuration(period)}
+	}
+}
+
+// Partition is a function to set the partitions. Partitions are a policy of
+// publishers and subscribers: set on a writer or reader, they apply to its
+// publisher or subscriber, and so to its other writers or readers.
+func Partition(names ...string) Policy {
+	return func(q *config.QoS) {
+		q.Partition = &config.Partition{Names: names}
+	}
+}
+
+// Raw is a function to set policies without a function here, given as XML
+// elements, e.g. "<lifespan><duration><sec>10</sec></duration></lifespan>"
+func Raw(xml string) Policy {
+	return func(q *config.QoS) {
+		q.Raw += xml
+	}
+}
@@ -0,0 +1,36 @@
+package qos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rticommunity/rticonnextdds-connector-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicies(t *testing.T) {
+	var q config.QoS
+	for _, policy := range []Policy{
+		Reliability(BestEffort),
+		History(KeepLast(10)),
+		Durability(TransientLocal),
+		Deadline(1500 * time.Millisecond),
+		Partition("a", "b"),
+		Raw("<lifespan/>"),
+		Raw("<ownership/>"),
+	} {
+		policy(&q)
+	}
+
+	assert.Equal(t, config.QoS{
+		Reliability: &config.Reliability{Kind: config.BestEffortReliability},
+		History:     &config.History{Kind: config.KeepLastHistory, Depth: 10},
+		Durability:  &config.Durability{Kind: config.TransientLocalDurability},
+		Deadline:    &config.Deadline{Period: config.Duration{Sec: 1, Nanosec: 500000000}},
+		Partition:   &config.Partition{Names: []string{"a", "b"}},
+		Raw:         "<lifespan/><ownership/>",
+	}, q)
+
+	History(KeepAll())(&q)
+	assert.Equal(t, &config.History{Kind: config.KeepAllHistory}, q.History)
+}
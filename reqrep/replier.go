@@ -0,0 +1,150 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+package reqrep
+
+import (
+	"context"
+	"errors"
+
+	rti "github.com/rticommunity/rticonnextdds-connector-go"
+)
+
+/********
+* Types *
+*********/
+
+// Handler computes the reply to a request. When it returns an error no reply
+// is sent for that request.
+type Handler[Req, Rep any] func(ctx context.Context, req Req) (Rep, error)
+
+// Replier receives requests of type Req through an Input and answers them
+// with replies of type Rep through an Output. Each reply carries the
+// identity of its request as related sample identity, which is what a
+// Requester uses to correlate it.
+type Replier[Req, Rep any] struct {
+	connector *rti.Connector
+	output    *rti.Output
+	input     *rti.Input
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// NewReplier creates a Replier reading requests with the Input named
+// inputName and writing replies with the Output named outputName
+func NewReplier[Req, Rep any](connector *rti.Connector, inputName, outputName string) (*Replier[Req, Rep], error) {
+	if connector == nil {
+		return nil, errors.New("connector is null")
+	}
+
+	input, err := connector.GetInput(inputName)
+	if err != nil {
+		return nil, err
+	}
+	output, err := connector.GetOutput(outputName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Replier[Req, Rep]{
+		connector: connector,
+		output:    output,
+		input:     input,
+	}, nil
+}
+
+// Serve takes requests and answers them with handler until ctx is done.
+// Requests are handled one at a time in the calling goroutine. Serve returns
+// the context error once ctx is done, or the first error writing a reply.
+// While it runs, it is a watcher of the Connector, see rti.Connector.Watch.
+func (replier *Replier[Req, Rep]) Serve(ctx context.Context, handler Handler[Req, Rep]) error {
+	if replier == nil {
+		return errors.New("replier is null")
+	}
+	if handler == nil {
+		return errors.New("handler is null")
+	}
+	release, err := replier.connector.Watch()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = poll(ctx.Done(), waitMs, replier.input.Wait, func() error {
+		return replier.serveAvailable(ctx, handler)
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+/********************
+* Private Functions *
+********************/
+
+// serveAvailable answers every request currently available in the Input
+func (replier *Replier[Req, Rep]) serveAvailable(ctx context.Context, handler Handler[Req, Rep]) error {
+	if err := replier.input.Take(); err != nil {
+		return nil
+	}
+
+	length, err := replier.input.Samples.GetLength()
+	if err != nil {
+		return nil
+	}
+
+	// Decode every request before running the handler, which may itself use
+	// the Connector and replace the samples of this Input
+	type request struct {
+		value    Req
+		identity rti.Identity
+	}
+	var requests []request
+	for i := 0; i < length; i++ {
+		valid, err := replier.input.Infos.IsValid(i)
+		if err != nil || !valid {
+			continue
+		}
+
+		var r request
+		if err := replier.input.Samples.Get(i, &r.value); err != nil {
+			continue
+		}
+		if r.identity, err = replier.input.Infos.GetIdentity(i); err != nil {
+			continue
+		}
+		requests = append(requests, r)
+	}
+
+	for _, r := range requests {
+		rep, err := handler(ctx, r.value)
+		if err != nil {
+			continue
+		}
+
+		if err := replier.reply(rep, r.identity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reply writes rep as the reply to the request with the given identity
+func (replier *Replier[Req, Rep]) reply(rep Rep, related rti.Identity) error {
+	if err := replier.output.Instance.Set(rep); err != nil {
+		return err
+	}
+
+	return replier.output.WriteWith(rti.WriteParams{RelatedSampleIdentity: &related})
+}
@@ -0,0 +1,149 @@
+package reqrep
+
+import (
+	"context"
+	"errors"
+	"path"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	rti "github.com/rticommunity/rticonnextdds-connector-go"
+	"github.com/rticommunity/rticonnextdds-connector-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	requestReplyProfile = "MyParticipantLibrary::TestRequestReply"
+	requestWriterName   = "TestPublisher::RequestWriter"
+	requestReaderName   = "TestSubscriber::RequestReader"
+	replyWriterName     = "TestPublisher::ReplyWriter"
+	replyReaderName     = "TestSubscriber::ReplyReader"
+)
+
+// Helper functions
+func newTestConnector(t *testing.T) *rti.Connector {
+	_, curPath, _, _ := runtime.Caller(0)
+	xmlPath := path.Join(path.Dir(curPath), "../test/xml/TestConnector1.xml")
+	connector, err := rti.NewConnector(requestReplyProfile, xmlPath)
+	assert.Nil(t, err)
+	assert.NotNil(t, connector)
+	return connector
+}
+
+// startReplier serves requests by swapping x and y until the returned
+// function is called
+func startReplier(t *testing.T) func() {
+	connector := newTestConnector(t)
+	replier, err := NewReplier[types.Shape, types.Shape](connector, requestReaderName, replyWriterName)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := replier.Serve(ctx, func(ctx context.Context, req types.Shape) (types.Shape, error) {
+			if req.Color == "IGNORED" {
+				return types.Shape{}, errors.New("ignored")
+			}
+			return types.Shape{Color: req.Color, X: req.Y, Y: req.X, Shapesize: req.Shapesize}, nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+		connector.Delete()
+	}
+}
+
+func TestRequestReply(t *testing.T) {
+	stop := startReplier(t)
+	defer stop()
+
+	connector := newTestConnector(t)
+	defer connector.Delete()
+	requester, err := NewRequester[types.Shape, types.Shape](connector, requestWriterName, replyReaderName)
+	assert.Nil(t, err)
+	defer requester.Close()
+	assert.NotEqual(t, [16]byte{}, requester.GUID())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rep, err := requester.Request(ctx, types.Shape{Color: "BLUE", X: 1, Y: 2, Shapesize: 30})
+	assert.Nil(t, err)
+	assert.Equal(t, types.Shape{Color: "BLUE", X: 2, Y: 1, Shapesize: 30}, rep)
+}
+
+// Tests that replies to concurrent in-flight requests are routed to the
+// caller that sent the matching request
+func TestConcurrentRequests(t *testing.T) {
+	stop := startReplier(t)
+	defer stop()
+
+	connector := newTestConnector(t)
+	defer connector.Delete()
+	requester, err := NewRequester[types.Shape, types.Shape](connector, requestWriterName, replyReaderName)
+	assert.Nil(t, err)
+	defer requester.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rep, err := requester.Request(ctx, types.Shape{Color: "RED", X: i, Y: 100 + i})
+			assert.Nil(t, err)
+			assert.Equal(t, 100+i, rep.X)
+			assert.Equal(t, i, rep.Y)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRequestTimeout(t *testing.T) {
+	stop := startReplier(t)
+	defer stop()
+
+	connector := newTestConnector(t)
+	defer connector.Delete()
+	requester, err := NewRequester[types.Shape, types.Shape](connector, requestWriterName, replyReaderName)
+	assert.Nil(t, err)
+	defer requester.Close()
+
+	// The replier does not answer this request
+	requester.Timeout = 500 * time.Millisecond
+	_, err = requester.Request(context.Background(), types.Shape{Color: "IGNORED"})
+	assert.ErrorIs(t, err, rti.ErrTimeout)
+
+	// Collecting several replies returns what arrived before the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	replies, err := requester.RequestMany(ctx, types.Shape{Color: "GREEN", X: 3}, 0)
+	assert.Nil(t, err)
+	assert.Len(t, replies, 1)
+}
+
+func TestRequesterClose(t *testing.T) {
+	connector := newTestConnector(t)
+	defer connector.Delete()
+	requester, err := NewRequester[types.Shape, types.Shape](connector, requestWriterName, replyReaderName)
+	assert.Nil(t, err)
+
+	assert.Nil(t, requester.Close())
+	assert.Nil(t, requester.Close())
+	_, err = requester.Request(context.Background(), types.Shape{Color: "BLUE"})
+	assert.ErrorIs(t, err, ErrClosed)
+
+	_, err = NewRequester[types.Shape, types.Shape](nil, requestWriterName, replyReaderName)
+	assert.NotNil(t, err)
+	_, err = NewReplier[types.Shape, types.Shape](connector, "invalidDR", replyWriterName)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,333 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package reqrep implements the request-reply pattern on top of RTI Connector
+// Outputs and Inputs. Requests are correlated with their replies through the
+// sample identity and related sample identity of each DDS sample.
+package reqrep
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	rti "github.com/rticommunity/rticonnextdds-connector-go"
+)
+
+/********
+* Types *
+*********/
+
+// Requester sends requests of type Req through an Output and receives replies
+// of type Rep through an Input. It is safe for concurrent use: every request
+// gets its own sequence number and replies are routed to the caller that
+// sent the matching request.
+//
+// The calls of a Requester into the native layer are serialized with the
+// other calls into the Connector, which may keep being used by other code
+// while the Requester is open, except for the Output and Input of the
+// Requester. It is a watcher of the Connector until it is closed, see
+// rti.Connector.Watch.
+type Requester[Req, Rep any] struct {
+	// Timeout is applied to requests whose context has no deadline.
+	// Zero means waiting until the context is cancelled.
+	Timeout time.Duration
+
+	output  *rti.Output
+	input   *rti.Input
+	guid    [16]byte
+	release func() // unregisters the Requester as a watcher of the Connector
+
+	// mutex protects the fields below, and keeps the samples of the Output
+	// and Input of the Requester from being replaced while they are used
+	mutex          sync.Mutex
+	sequenceNumber int
+	pending        map[int]chan reply[Rep]
+	closed         bool
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+type reply[Rep any] struct {
+	value Rep
+	err   error
+}
+
+// replyBufferSize bounds the number of replies buffered for one request that
+// the caller has not consumed yet. Extra replies are discarded.
+const replyBufferSize = 32
+
+// waitMs bounds how long the receive goroutine of a Requester and
+// Replier.Serve block in the native layer. They hold the mutex of the
+// Connector meanwhile, so it also bounds how long they delay other calls.
+const waitMs = 10
+
+// pollMs delays the retries of failed waits
+const pollMs = 100
+
+// ErrClosed is returned when a Requester is used after Close
+var ErrClosed = errors.New("requester is closed")
+
+/*******************
+* Public Functions *
+*******************/
+
+// NewRequester creates a Requester writing requests with the Output named
+// outputName and reading replies with the Input named inputName.
+//
+// The native layer does not expose the GUID of a DataWriter, so the Requester
+// identifies its requests with a randomly generated 16-byte GUID that is
+// unique to this Requester. Sequence numbers start at 1 and increase by one
+// for every request.
+//
+// Close must be called before the Connector is deleted.
+func NewRequester[Req, Rep any](connector *rti.Connector, outputName, inputName string) (*Requester[Req, Rep], error) {
+	if connector == nil {
+		return nil, errors.New("connector is null")
+	}
+
+	output, err := connector.GetOutput(outputName)
+	if err != nil {
+		return nil, err
+	}
+	input, err := connector.GetInput(inputName)
+	if err != nil {
+		return nil, err
+	}
+
+	requester := &Requester[Req, Rep]{
+		output:  output,
+		input:   input,
+		pending: make(map[int]chan reply[Rep]),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if _, err := rand.Read(requester.guid[:]); err != nil {
+		return nil, err
+	}
+	if requester.release, err = connector.Watch(); err != nil {
+		return nil, err
+	}
+
+	go requester.receive()
+
+	return requester, nil
+}
+
+// GUID returns the writer GUID used to identify the requests of this Requester
+func (requester *Requester[Req, Rep]) GUID() [16]byte {
+	return requester.guid
+}
+
+// Request sends req and waits for the first reply
+func (requester *Requester[Req, Rep]) Request(ctx context.Context, req Req) (Rep, error) {
+	var rep Rep
+
+	replies, err := requester.RequestMany(ctx, req, 1)
+	if err != nil {
+		return rep, err
+	}
+
+	return replies[0], nil
+}
+
+// RequestMany sends req and collects replies until maxReplies have been
+// received or the context is done. If the context is done after at least one
+// reply has been received, the replies received so far are returned without
+// error. A maxReplies of zero or less collects replies until the context is
+// done.
+func (requester *Requester[Req, Rep]) RequestMany(ctx context.Context, req Req, maxReplies int) ([]Rep, error) {
+	if requester == nil {
+		return nil, errors.New("requester is null")
+	}
+
+	if _, ok := ctx.Deadline(); !ok && requester.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requester.Timeout)
+		defer cancel()
+	}
+
+	sequenceNumber, replies, err := requester.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer requester.forget(sequenceNumber)
+
+	var received []Rep
+	for maxReplies <= 0 || len(received) < maxReplies {
+		select {
+		case r := <-replies:
+			if r.err != nil {
+				return received, r.err
+			}
+			received = append(received, r.value)
+		case <-requester.done:
+			return received, ErrClosed
+		case <-ctx.Done():
+			if len(received) > 0 {
+				return received, nil
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, rti.ErrTimeout
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	return received, nil
+}
+
+// Close stops receiving replies. Pending requests return ErrClosed.
+func (requester *Requester[Req, Rep]) Close() error {
+	if requester == nil {
+		return errors.New("requester is null")
+	}
+
+	requester.mutex.Lock()
+	if requester.closed {
+		requester.mutex.Unlock()
+		return nil
+	}
+	requester.closed = true
+	close(requester.done)
+	requester.mutex.Unlock()
+
+	<-requester.stopped
+	return nil
+}
+
+/********************
+* Private Functions *
+********************/
+
+// send writes a request and registers the channel its replies are routed to
+func (requester *Requester[Req, Rep]) send(req Req) (int, chan reply[Rep], error) {
+	requester.mutex.Lock()
+	defer requester.mutex.Unlock()
+
+	if requester.closed {
+		return 0, nil, ErrClosed
+	}
+
+	requester.sequenceNumber++
+	identity := rti.Identity{
+		WriterGUID:     requester.guid,
+		SequenceNumber: requester.sequenceNumber,
+	}
+
+	if err := requester.output.Instance.Set(req); err != nil {
+		return 0, nil, err
+	}
+
+	replies := make(chan reply[Rep], replyBufferSize)
+	requester.pending[identity.SequenceNumber] = replies
+
+	if err := requester.output.WriteWithIdentity(identity); err != nil {
+		delete(requester.pending, identity.SequenceNumber)
+		return 0, nil, err
+	}
+
+	return identity.SequenceNumber, replies, nil
+}
+
+// forget stops routing replies for a request
+func (requester *Requester[Req, Rep]) forget(sequenceNumber int) {
+	requester.mutex.Lock()
+	delete(requester.pending, sequenceNumber)
+	requester.mutex.Unlock()
+}
+
+// receive takes replies until the Requester is closed and routes each of
+// them to the request it is related to
+func (requester *Requester[Req, Rep]) receive() {
+	defer close(requester.stopped)
+	defer requester.release()
+
+	poll(requester.done, waitMs, requester.input.Wait, func() error {
+		requester.mutex.Lock()
+		requester.dispatch()
+		requester.mutex.Unlock()
+		return nil
+	})
+}
+
+// poll is a function to call wait with timeoutMs until done is closed, and
+// onData every time wait returns without error. Failures of wait other than
+// a timeout are retried after pollMs. poll returns the first error of onData,
+// or nil once done is closed.
+func poll(done <-chan struct{}, timeoutMs int, wait func(timeoutMs int) error, onData func() error) error {
+	for {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+
+		err := wait(timeoutMs)
+		if err == rti.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			// Avoid spinning when the native layer keeps failing
+			select {
+			case <-time.After(pollMs * time.Millisecond):
+			case <-done:
+			}
+			continue
+		}
+
+		if err := onData(); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch takes the available replies and routes them. It must be called
+// with the mutex held.
+func (requester *Requester[Req, Rep]) dispatch() {
+	if err := requester.input.Take(); err != nil {
+		return
+	}
+
+	length, err := requester.input.Samples.GetLength()
+	if err != nil {
+		return
+	}
+
+	for i := 0; i < length; i++ {
+		valid, err := requester.input.Infos.IsValid(i)
+		if err != nil || !valid {
+			continue
+		}
+
+		related, err := requester.input.Infos.GetRelatedIdentity(i)
+		if err != nil || related.WriterGUID != requester.guid {
+			// Not a reply to this Requester
+			continue
+		}
+
+		replies, ok := requester.pending[related.SequenceNumber]
+		if !ok {
+			// The request has already completed
+			continue
+		}
+
+		var r reply[Rep]
+		r.err = requester.input.Samples.Get(i, &r.value)
+
+		select {
+		case replies <- r:
+		default:
+		}
+	}
+}
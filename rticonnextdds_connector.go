@@ -16,7 +16,17 @@ package rti
 import "C"
 import (
 	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"unsafe"
+
+	"github.com/rticommunity/rticonnextdds-connector-go/config"
+	"github.com/rticommunity/rticonnextdds-connector-go/qos"
 )
 
 /********
@@ -29,15 +39,124 @@ var ErrNoData = errors.New("DDS Exception: No Data")
 // ErrTimeout is returned when there is a timeout in the DDS layer
 var ErrTimeout = errors.New("DDS Exception: Timeout")
 
+// ErrConfigNotFound is returned by NewConnector when an XML file of its URL,
+// or one of their includes, does not exist
+var ErrConfigNotFound = errors.New("XML configuration not found")
+
+// ErrInvalidXML is returned by NewConnector when its XML configuration cannot
+// be parsed
+var ErrInvalidXML = errors.New("invalid XML configuration")
+
+// ErrParticipantNotFound is returned by NewConnector when its configuration
+// has no participant named configName
+var ErrParticipantNotFound = errors.New("participant configuration not found")
+
+// ErrInvalidConfig is returned by NewConnector when the participant cannot be
+// created from a valid XML document, e.g. for a type or QoS error or a
+// security plugin failure
+var ErrInvalidConfig = errors.New("invalid participant configuration")
+
+// ErrOutputNotFound is returned by GetOutput when the participant has no such
+// data writer
+var ErrOutputNotFound = errors.New("invalid Publication::DataWriter name")
+
+// ErrInputNotFound is returned by GetInput when the participant has no such
+// data reader
+var ErrInputNotFound = errors.New("invalid Subscription::DataReader name")
+
+// ErrConnectorInUse is returned by the functions creating the participant of
+// a connector again, such as SetPartitions, while goroutines started by
+// MatchEvents, InstanceEvents, LastValueCache.Run or Watch still use it
+var ErrConnectorInUse = errors.New("connector is in use by background goroutines")
+
 /********
 * Types *
 *********/
 
 // Connector is a container managing DDS inputs and outputs
 type Connector struct {
+	// mutex serializes the calls into the native layer, which is not
+	// thread-safe, including those of the goroutines started by MatchEvents,
+	// InstanceEvents, LastValueCache.Run and reqrep
+	mutex   sync.Mutex
 	native  *C.RTI_Connector
 	Inputs  []Input
 	Outputs []Output
+	// configName and url the connector was created from, to list its
+	// inputs and outputs in errors
+	configName string
+	url        string
+	// configURL and options given to NewConnector, to create the
+	// participant again
+	configURL string
+	options   connectorOptions
+	// inputs and outputs returned by GetInput and GetOutput
+	inputs  []*Input
+	outputs []*Output
+	// watchers is the number of goroutines using the inputs and outputs in
+	// the background, during which the participant is not created again
+	watchersMutex sync.Mutex
+	watchers      int
+}
+
+// ConnectorError is the error returned by NewConnector. Kind classifies the
+// failure and is matched by errors.Is, e.g.:
+//
+//	if errors.Is(err, rti.ErrParticipantNotFound) {
+type ConnectorError struct {
+	// Kind is ErrConfigNotFound, ErrInvalidXML, ErrParticipantNotFound or
+	// ErrInvalidConfig
+	Kind       error
+	ConfigName string
+	URL        string
+	// Cause explains the failure when it was found by the Go layer, e.g.
+	// the position of an XML syntax error
+	Cause error
+	// Message is the last error message of the native layer
+	Message string
+}
+
+// Version describes the native libraries loaded by the process
+type Version struct {
+	// Core is the version of the RTI Connext DDS core libraries (e.g., 7.3.0.2)
+	Core string
+	// Connector is the version of the RTI Connector native library
+	Connector string
+	// CoreBuild and ConnectorBuild are the full build identifiers
+	CoreBuild      string
+	ConnectorBuild string
+}
+
+// Option is a function configuring the creation of a Connector
+type Option func(*connectorOptions)
+
+type connectorOptions struct {
+	expandVars bool
+	vars       map[string]string
+	qos        QoSOverrides
+	// partitions set by SetPartitions, by "publisher:Name" and
+	// "subscriber:Name"
+	partitions map[string][]string
+	// content filters of readers, by "Subscriber::DataReader" name
+	filters map[string]contentFilter
+}
+
+// contentFilter is a builtin.sql filter expression of a data reader, with
+// its %0, %1... parameters
+type contentFilter struct {
+	expression string
+	parameters []string
+}
+
+// QoSOverrides are QoS policies applied on top of the XML profiles of the
+// participant created by NewConnector, so that they can be tuned without
+// editing the XML, e.g. from flags
+type QoSOverrides struct {
+	Participant []qos.Policy
+	// Writers and Readers are keyed by "Publisher::DataWriter" and
+	// "Subscriber::DataReader" names, as in GetOutput and GetInput
+	Writers map[string][]qos.Policy
+	Readers map[string][]qos.Policy
 }
 
 // SampleHandler is an User defined function type that takes in pointers of
@@ -67,7 +186,23 @@ const (
 // If you omit the URL schema name, Connector will assume a file name. For example:
 //
 //	File Specification: /usr/local/default_dds.xml
-func NewConnector(configName, url string) (*Connector, error) {
+//
+// Options such as WithConfigVars and WithReaderQoS pre-process the XML
+// documents before they are passed to the native layer.
+func NewConnector(configName, url string, opts ...Option) (*Connector, error) {
+	originalURL := url
+	var options connectorOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.expandVars || options.rewritesXML() {
+		preprocessed, err := preprocessConfigURL(configName, url, options)
+		if err != nil {
+			return nil, err
+		}
+		url = preprocessed
+	}
+
 	connector := new(Connector)
 
 	configNameCStr := C.CString(configName)
@@ -77,18 +212,203 @@ func NewConnector(configName, url string) (*Connector, error) {
 
 	connector.native = C.RTI_Connector_new(configNameCStr, urlCStr, nil)
 	if connector.native == nil {
-		return nil, errors.New("invalid participant profile, xml path or xml profile")
+		connectorErr := &ConnectorError{
+			ConfigName: configName,
+			URL:        originalURL,
+			Message:    lastErrorMessage(),
+		}
+		connectorErr.Kind, connectorErr.Cause = diagnoseConfig(configName, url)
+		return nil, connectorErr
 	}
+	connector.configName = configName
+	connector.url = url
+	connector.configURL = originalURL
+	connector.options = options
 
 	return connector, nil
 }
 
+// NewConnectorFromConfig is a constructor of Connector from a configuration
+// built with the config package instead of an XML document.
+//
+// configName is the participant to create, as in NewConnector, e.g.
+// "MyParticipantLibrary::MyParticipant".
+func NewConnectorFromConfig(configName string, cfg *config.Config, opts ...Option) (*Connector, error) {
+	if cfg == nil {
+		return nil, errors.New("config is null")
+	}
+
+	url, err := cfg.URL()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConnector(configName, url, opts...)
+}
+
+// WithConfigVars is an option of NewConnector to expand the variables of
+// the XML configuration, so that one document can serve several
+// environments:
+//
+//	<domain name="MyDomain" domain_id="$(DOMAIN_ID)">
+//	<element>${env:INITIAL_PEER}</element>
+//
+// $(VAR) is replaced by vars[VAR], or else by the environment variable VAR,
+// and ${env:VAR} by the environment variable VAR. Variables defined nowhere
+// make NewConnector fail. Files, including their <include> elements, are
+// expanded as well as str:// documents.
+func WithConfigVars(vars map[string]string) Option {
+	return func(options *connectorOptions) {
+		options.expandVars = true
+		options.vars = vars
+	}
+}
+
+// WithQoSOverrides is an option of NewConnector to apply QoS policies on top
+// of the XML profiles of the participant, its writers and its readers.
+// Policies given by several options are applied in order.
+func WithQoSOverrides(overrides QoSOverrides) Option {
+	return func(options *connectorOptions) {
+		options.qos.Participant = append(options.qos.Participant, overrides.Participant...)
+		for name, policies := range overrides.Writers {
+			if options.qos.Writers == nil {
+				options.qos.Writers = make(map[string][]qos.Policy)
+			}
+			options.qos.Writers[name] = append(options.qos.Writers[name], policies...)
+		}
+		for name, policies := range overrides.Readers {
+			if options.qos.Readers == nil {
+				options.qos.Readers = make(map[string][]qos.Policy)
+			}
+			options.qos.Readers[name] = append(options.qos.Readers[name], policies...)
+		}
+	}
+}
+
+// WithParticipantQoS is an option of NewConnector to apply QoS policies to
+// the participant
+func WithParticipantQoS(policies ...qos.Policy) Option {
+	return WithQoSOverrides(QoSOverrides{Participant: policies})
+}
+
+// WithWriterQoS is an option of NewConnector to apply QoS policies to the
+// data writer outputName, e.g. "MyPublisher::MyWriter"
+func WithWriterQoS(outputName string, policies ...qos.Policy) Option {
+	return WithQoSOverrides(QoSOverrides{Writers: map[string][]qos.Policy{outputName: policies}})
+}
+
+// WithReaderQoS is an option of NewConnector to apply QoS policies to the
+// data reader inputName, e.g.:
+//
+//	rti.WithReaderQoS("MySubscriber::MyReader", qos.History(qos.KeepLast(10)))
+func WithReaderQoS(inputName string, policies ...qos.Policy) Option {
+	return WithQoSOverrides(QoSOverrides{Readers: map[string][]qos.Policy{inputName: policies}})
+}
+
+// WithContentFilter is an option of NewConnector to filter the samples of the
+// data reader inputName with an SQL expression, such as
+// "color = 'BLUE' AND x > %0", and its parameters. The filter replaces the
+// <content_filter> of the reader in the XML configuration, if any. String
+// parameters are quoted, e.g. "'BLUE'". Where DDS supports it, the filter is
+// evaluated by the matched writers, so samples are not sent to the reader at
+// all.
+func WithContentFilter(inputName, expression string, params []string) Option {
+	return func(options *connectorOptions) {
+		options.filters = maps.Clone(options.filters)
+		if options.filters == nil {
+			options.filters = make(map[string]contentFilter)
+		}
+		options.filters[inputName] = contentFilter{expression: expression, parameters: slices.Clone(params)}
+	}
+}
+
+// NewConnectorFS is a constructor of Connector from XML documents of a file
+// system, such as an embed.FS, so that binaries do not depend on XML files on
+// disk:
+//
+//	//go:embed xml
+//	var xmlFS embed.FS
+//
+//	connector, err := rti.NewConnectorFS(xmlFS, "MyParticipantLibrary::Zero", "xml/ShapeExample.xml")
+//
+// The documents are merged in order, as if listed in a single XML file.
+// <include file="..."/> elements are resolved within fsys, relative to the
+// including document.
+func NewConnectorFS(fsys fs.FS, configName string, paths ...string) (*Connector, error) {
+	return NewConnectorFSWithOptions(fsys, configName, paths)
+}
+
+// NewConnectorFSWithOptions is a constructor of Connector from XML documents
+// of a file system, as NewConnectorFS, applying opts to the merged document
+// as NewConnector does, e.g.:
+//
+//	connector, err := rti.NewConnectorFSWithOptions(xmlFS, "MyParticipantLibrary::Zero",
+//		[]string{"xml/ShapeExample.xml"}, rti.WithConfigVars(vars))
+func NewConnectorFSWithOptions(fsys fs.FS, configName string, paths []string, opts ...Option) (*Connector, error) {
+	document, err := readXMLFS(fsys, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConnector(configName, `str://"`+document+`"`, opts...)
+}
+
+// Error is a function to describe the failure of NewConnector
+func (err *ConnectorError) Error() string {
+	url := err.URL
+	if len(url) > 64 {
+		url = url[:61] + "..."
+	}
+	msg := fmt.Sprintf("cannot create participant %q from %q: %v", err.ConfigName, url, err.Kind)
+	if err.Cause != nil {
+		msg += ": " + err.Cause.Error()
+	}
+	if err.Message != "" {
+		msg += " (" + strings.TrimSpace(err.Message) + ")"
+	}
+	return msg
+}
+
+// Unwrap is a function to return the kind of the failure, for errors.Is
+func (err *ConnectorError) Unwrap() error {
+	return err.Kind
+}
+
+// NativeVersion is a function to get the versions of the native libraries
+// loaded by the process, for instance to log them at startup
+func NativeVersion() (Version, error) {
+	var coreCStr, connectorCStr *C.char
+
+	retcode := int(C.RTI_Connector_get_build_versions(&coreCStr, &connectorCStr))
+	if err := checkRetcode(retcode); err != nil {
+		return Version{}, err
+	}
+
+	// The strings are static in the native library and must not be freed
+	version := Version{
+		CoreBuild:      C.GoString(coreCStr),
+		ConnectorBuild: C.GoString(connectorCStr),
+	}
+	version.Core = versionNumber.FindString(version.CoreBuild)
+	version.Connector = versionNumber.FindString(version.ConnectorBuild)
+
+	return version, nil
+}
+
+// String returns the versions in a form suitable for logs
+func (version Version) String() string {
+	return "RTI Connext DDS " + version.Core + ", RTI Connector " + version.Connector
+}
+
 // Delete is a destructor of Connector
 func (connector *Connector) Delete() error {
 	if connector == nil {
 		return errors.New("connector is null")
 	}
 
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+
 	// Delete memory allocated in C layer
 	for _, input := range connector.Inputs {
 		C.free(unsafe.Pointer(input.nameCStr))
@@ -121,12 +441,53 @@ func (connector *Connector) GetInput(inputName string) (*Input, error) {
 	return newInput(connector, inputName)
 }
 
+// NewFilteredInput returns an input object for the data reader inputName,
+// filtering its samples as set by WithContentFilter, e.g.:
+//
+//	input, err := connector.NewFilteredInput("MySubscriber::MySquareReader",
+//		"color = 'BLUE' AND x > %0", []string{"50"})
+//
+// The Connector API cannot create readers in an existing participant, so the
+// participant of the connector is created again, as by Input.SetPartitions:
+// every input of the connector loses the samples not taken yet, every output
+// the fields set in its Instance, and ErrConnectorInUse is returned while
+// watchers use the connector. Giving the filter to NewConnector with
+// WithContentFilter avoids that.
+func (connector *Connector) NewFilteredInput(inputName, expression string, params []string) (*Input, error) {
+	if connector == nil {
+		return nil, errors.New("connector is null")
+	}
+
+	filter := contentFilter{expression: expression, parameters: params}
+	if err := connector.setContentFilter(inputName, filter); err != nil {
+		return nil, err
+	}
+
+	return newInput(connector, inputName)
+}
+
+// Watch is a function to register a goroutine using the inputs or outputs of
+// the connector in the background, such as the one receiving the replies of a
+// reqrep.Requester. Until release is called, the functions creating the
+// participant again, such as SetPartitions, fail with ErrConnectorInUse
+// rather than deleting the readers and writers under the goroutine.
+func (connector *Connector) Watch() (release func(), err error) {
+	if connector == nil {
+		return nil, errors.New("connector is null")
+	}
+
+	return connector.watch(), nil
+}
+
 // Wait is a function to block until data is available on an input
 func (connector *Connector) Wait(timeoutMs int) error {
 	if connector == nil {
 		return errors.New("connector is null")
 	}
 
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_wait_for_data(unsafe.Pointer(connector.native), C.int(timeoutMs)))
 	return checkRetcode(retcode)
 }
@@ -135,9 +496,16 @@ func (connector *Connector) Wait(timeoutMs int) error {
 * Private Functions *
 ********************/
 
+// versionNumber matches the version in a build identifier such as
+// RTICONNECTOR_BUILD_7.3.0.2_20240723T000000Z_RTI_REL
+var versionNumber = regexp.MustCompile(`[0-9]+(\.[0-9]+)+`)
+
 func newOutput(connector *Connector, outputName string) (*Output, error) {
 	// Error checking for the connector is skipped because it was already checked
 
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+
 	output := new(Output)
 	output.connector = connector
 
@@ -145,12 +513,14 @@ func newOutput(connector *Connector, outputName string) (*Output, error) {
 
 	output.native = C.RTI_Connector_get_datawriter(unsafe.Pointer(connector.native), output.nameCStr)
 	if output.native == nil {
-		return nil, errors.New("invalid Publication::DataWriter name")
+		C.free(unsafe.Pointer(output.nameCStr))
+		return nil, entityNotFound(ErrOutputNotFound, outputName, entityNames(connector.configName, connector.url, true))
 	}
 	output.name = outputName
 	output.Instance = newInstance(output)
 
 	connector.Outputs = append(connector.Outputs, *output)
+	connector.outputs = append(connector.outputs, output)
 
 	return output, nil
 }
@@ -158,6 +528,9 @@ func newOutput(connector *Connector, outputName string) (*Output, error) {
 func newInput(connector *Connector, inputName string) (*Input, error) {
 	// Error checking for the connector is skipped because it was already checked
 
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+
 	input := new(Input)
 	input.connector = connector
 
@@ -165,17 +538,141 @@ func newInput(connector *Connector, inputName string) (*Input, error) {
 
 	input.native = C.RTI_Connector_get_datareader(unsafe.Pointer(connector.native), input.nameCStr)
 	if input.native == nil {
-		return nil, errors.New("invalid Subscription::DataReader name")
+		C.free(unsafe.Pointer(input.nameCStr))
+		return nil, entityNotFound(ErrInputNotFound, inputName, entityNames(connector.configName, connector.url, false))
 	}
 	input.name = inputName
 	input.Samples = newSamples(input)
 	input.Infos = newInfos(input)
 
 	connector.Inputs = append(connector.Inputs, *input)
+	connector.inputs = append(connector.inputs, input)
 
 	return input, nil
 }
 
+// entityNotFound is a function to return kind for the input or output name,
+// with the names available instead
+func entityNotFound(kind error, name string, available []string) error {
+	if len(available) == 0 {
+		return fmt.Errorf("%w %q", kind, name)
+	}
+	return fmt.Errorf("%w %q, available: %s", kind, name, strings.Join(available, ", "))
+}
+
+// watch is a function to register a watcher of the connector, and to return
+// the function unregistering it
+func (connector *Connector) watch() func() {
+	connector.watchersMutex.Lock()
+	connector.watchers++
+	connector.watchersMutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			connector.watchersMutex.Lock()
+			connector.watchers--
+			connector.watchersMutex.Unlock()
+		})
+	}
+}
+
+// recreate is a function to create the participant of the connector again
+// with options, and to point its inputs and outputs to the new readers and
+// writers. The connector is left unchanged if the participant cannot be
+// created, or if watchers still use its inputs and outputs.
+func (connector *Connector) recreate(options connectorOptions) error {
+	// Holding the mutex keeps new watchers from starting meanwhile
+	connector.watchersMutex.Lock()
+	defer connector.watchersMutex.Unlock()
+	if connector.watchers > 0 {
+		return ErrConnectorInUse
+	}
+
+	url, err := preprocessConfigURL(connector.configName, connector.configURL, options)
+	if err != nil {
+		return err
+	}
+
+	configNameCStr := C.CString(connector.configName)
+	defer C.free(unsafe.Pointer(configNameCStr))
+	urlCStr := C.CString(url)
+	defer C.free(unsafe.Pointer(urlCStr))
+
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+
+	native := C.RTI_Connector_new(configNameCStr, urlCStr, nil)
+	if native == nil {
+		connectorErr := &ConnectorError{
+			ConfigName: connector.configName,
+			URL:        connector.configURL,
+			Message:    lastErrorMessage(),
+		}
+		connectorErr.Kind, connectorErr.Cause = diagnoseConfig(connector.configName, url)
+		return connectorErr
+	}
+
+	C.RTI_Connector_delete(connector.native)
+	connector.native = native
+	connector.url = url
+	connector.options = options
+
+	for _, input := range connector.inputs {
+		input.native = C.RTI_Connector_get_datareader(unsafe.Pointer(native), input.nameCStr)
+	}
+	for i := range connector.Inputs {
+		connector.Inputs[i].native = C.RTI_Connector_get_datareader(unsafe.Pointer(native), connector.Inputs[i].nameCStr)
+	}
+	for _, output := range connector.outputs {
+		output.native = C.RTI_Connector_get_datawriter(unsafe.Pointer(native), output.nameCStr)
+	}
+	for i := range connector.Outputs {
+		connector.Outputs[i].native = C.RTI_Connector_get_datawriter(unsafe.Pointer(native), connector.Outputs[i].nameCStr)
+	}
+
+	return nil
+}
+
+// setPartitions is a function to create the participant again with the
+// publisher or subscriber group ("publisher:Name" or "subscriber:Name") in
+// partitions
+func (connector *Connector) setPartitions(group string, partitions []string) error {
+	options := connector.options
+	options.partitions = maps.Clone(options.partitions)
+	if options.partitions == nil {
+		options.partitions = make(map[string][]string)
+	}
+	options.partitions[group] = slices.Clone(partitions)
+
+	return connector.recreate(options)
+}
+
+// setContentFilter is a function to create the participant again with the
+// data reader inputName filtered by filter, unless it already is
+func (connector *Connector) setContentFilter(inputName string, filter contentFilter) error {
+	if current, ok := connector.options.filters[inputName]; ok &&
+		current.expression == filter.expression && slices.Equal(current.parameters, filter.parameters) {
+		return nil
+	}
+
+	options := connector.options
+	WithContentFilter(inputName, filter.expression, filter.parameters)(&options)
+
+	return connector.recreate(options)
+}
+
+// rewritesXML is a function to tell whether options change the XML
+// configuration given to NewConnector
+func (options connectorOptions) rewritesXML() bool {
+	return !options.qos.empty() || len(options.partitions) > 0 || len(options.filters) > 0
+}
+
+// empty is a function to tell whether overrides has no policy
+func (overrides QoSOverrides) empty() bool {
+	return len(overrides.Participant) == 0 && len(overrides.Writers) == 0 && len(overrides.Readers) == 0
+}
+
 func newInstance(output *Output) *Instance {
 	// Error checking for the output is skipped because it was already checked
 	return &Instance{
@@ -197,6 +694,12 @@ func newInfos(input *Input) *Infos {
 	}
 }
 
+// lastErrorMessage is a function to return the last error message of the
+// native layer
+func lastErrorMessage() string {
+	return C.GoString((*C.char)(C.RTI_Connector_get_last_error_message()))
+}
+
 // checkRetcode is a function to check return code
 func checkRetcode(retcode int) error {
 	switch retcode {
@@ -206,7 +709,7 @@ func checkRetcode(retcode int) error {
 	case DDSRetCodeTimeout:
 		return ErrTimeout
 	default:
-		return errors.New("DDS Exception: " + C.GoString((*C.char)(C.RTI_Connector_get_last_error_message())))
+		return errors.New("DDS Exception: " + lastErrorMessage())
 	}
 	return nil
 }
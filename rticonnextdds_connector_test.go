@@ -1,12 +1,23 @@
 package rti
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
 	"math"
+	"os"
 	"path"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	"github.com/rticommunity/rticonnextdds-connector-go/config"
+	"github.com/rticommunity/rticonnextdds-connector-go/qos"
 	"github.com/rticommunity/rticonnextdds-connector-go/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -31,15 +42,429 @@ func newTestOutput(connector *Connector) (*Output, error) {
 	return connector.GetOutput("MyPublisher::MyWriter")
 }
 
+// newTestConfig returns the configuration of test/xml/Test.xml, restricted to
+// the st and l members, built with the config package
+func newTestConfig() *config.Config {
+	reliable := &config.QoS{
+		Reliability: &config.Reliability{Kind: config.ReliableReliability},
+		History:     &config.History{Kind: config.KeepAllHistory},
+		Durability:  &config.Durability{Kind: config.TransientLocalDurability},
+	}
+	return &config.Config{
+		QoSLibraries: []config.QoSLibrary{{
+			Name: "QosLibrary",
+			Profiles: []config.QoSProfile{{
+				Name:           "DefaultProfile",
+				IsDefaultQoS:   true,
+				ParticipantQoS: &config.QoS{Discovery: &config.Discovery{InitialPeers: []string{"shmem://"}}},
+				DataWriterQoS:  reliable,
+				DataReaderQoS:  reliable,
+			}},
+		}},
+		Types: &config.Types{Structs: []config.Struct{{
+			Name:          "TestType",
+			Extensibility: config.Extensible,
+			Members: []config.Member{
+				{Name: "st", Type: config.String, StringMaxLength: 128, Key: true},
+				{Name: "l", Type: config.Int32},
+			},
+		}}},
+		DomainLibraries: []config.DomainLibrary{{
+			Name: "MyDomainLibrary",
+			Domains: []config.Domain{{
+				Name:          "MyDomain",
+				RegisterTypes: []config.RegisterType{{Name: "TestType", TypeRef: "TestType"}},
+				Topics:        []config.Topic{{Name: "Test", RegisterTypeRef: "TestType"}},
+			}},
+		}},
+		ParticipantLibraries: []config.ParticipantLibrary{{
+			Name: "MyParticipantLibrary",
+			Participants: []config.Participant{{
+				Name:        "Zero",
+				DomainRef:   "MyDomainLibrary::MyDomain",
+				Publishers:  []config.Publisher{{Name: "MyPublisher", Writers: []config.DataWriter{{Name: "MyWriter", TopicRef: "Test"}}}},
+				Subscribers: []config.Subscriber{{Name: "MySubscriber", Readers: []config.DataReader{{Name: "MyReader", TopicRef: "Test"}}}},
+			}},
+		}},
+	}
+}
+
 // Connector test
 
+// This test function ensures that a Connector can be created from a configuration built in Go
+func TestConnectorFromConfig(t *testing.T) {
+	connector, err := NewConnectorFromConfig(participantProfile, newTestConfig())
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	assert.Nil(t, output.Instance.SetString("st", "from_config"))
+	assert.Nil(t, output.Instance.SetInt32("l", 42))
+	assert.Nil(t, output.Write())
+	assert.Nil(t, connector.Wait(-1))
+	assert.Nil(t, input.Take())
+
+	st, err := input.Samples.GetString(0, "st")
+	assert.Nil(t, err)
+	assert.Equal(t, "from_config", st)
+	l, err := input.Samples.GetInt32(0, "l")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(42), l)
+
+	_, err = NewConnectorFromConfig(participantProfile, nil)
+	assert.NotNil(t, err)
+	_, err = NewConnectorFromConfig(invalidParticipantProfile, newTestConfig())
+	assert.NotNil(t, err)
+}
+
+// This test function ensures that a Connector can be created from XML files of a file system
+func TestConnectorFS(t *testing.T) {
+	testXML, err := os.ReadFile("test/xml/Test.xml")
+	assert.Nil(t, err)
+	fsys := fstest.MapFS{
+		"app.xml":          {Data: []byte(`<?xml version="1.0"?><dds><include file="xml/Test.xml"/></dds>`)},
+		"xml/Test.xml":     {Data: testXML},
+		"cycle/a.xml":      {Data: []byte(`<dds><include file="b.xml"/></dds>`)},
+		"cycle/b.xml":      {Data: []byte(`<dds><include file="a.xml"/></dds>`)},
+		"invalid_root.xml": {Data: []byte(`<types/>`)},
+	}
+
+	connector, err := NewConnectorFS(fsys, participantProfile, "app.xml")
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	assert.Nil(t, output.Instance.SetString("st", "from_fs"))
+	assert.Nil(t, output.Write())
+	assert.Nil(t, connector.Wait(-1))
+	assert.Nil(t, input.Take())
+	st, err := input.Samples.GetString(0, "st")
+	assert.Nil(t, err)
+	assert.Equal(t, "from_fs", st)
+
+	// Options are applied to the merged document
+	filtered, err := NewConnectorFSWithOptions(fsys, participantProfile, []string{"app.xml"}, WithContentFilter("MySubscriber::MyReader", "l > 0", nil))
+	assert.Nil(t, err)
+	defer filtered.Delete()
+	assert.Contains(t, filtered.options.filters, "MySubscriber::MyReader")
+
+	_, err = NewConnectorFS(fsys, participantProfile, "cycle/a.xml")
+	assert.NotNil(t, err)
+	_, err = NewConnectorFS(fsys, participantProfile, "invalid_root.xml")
+	assert.NotNil(t, err)
+	_, err = NewConnectorFS(fsys, participantProfile, "missing.xml")
+	assert.NotNil(t, err)
+	_, err = NewConnectorFS(fsys, participantProfile)
+	assert.NotNil(t, err)
+	_, err = NewConnectorFS(nil, participantProfile, "app.xml")
+	assert.NotNil(t, err)
+}
+
+// This test function ensures that the variables of a configuration are expanded with WithConfigVars
+func TestConnectorConfigVars(t *testing.T) {
+	testXML, err := os.ReadFile("test/xml/Test.xml")
+	assert.Nil(t, err)
+	xml := strings.Replace(string(testXML), `domain_id="0"`, `domain_id="$(DOMAIN_ID)"`, 1)
+	xml = strings.Replace(xml, "<element>shmem://</element>", "<element>${env:TEST_INITIAL_PEER}</element>", 1)
+	xmlPath := filepath.Join(t.TempDir(), "Test.xml")
+	assert.Nil(t, os.WriteFile(xmlPath, []byte(xml), 0644))
+	t.Setenv("TEST_INITIAL_PEER", "shmem://")
+	vars := map[string]string{"DOMAIN_ID": "0"}
+
+	connector, err := NewConnector(participantProfile, xmlPath, WithConfigVars(vars))
+	assert.Nil(t, err)
+	assert.Nil(t, connector.Delete())
+
+	connector, err = NewConnector(participantProfile, `str://"`+xml[strings.Index(xml, "<dds"):]+`"`, WithConfigVars(vars))
+	assert.Nil(t, err)
+	assert.Nil(t, connector.Delete())
+
+	_, err = NewConnector(participantProfile, xmlPath, WithConfigVars(nil))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "$(DOMAIN_ID)")
+
+	t.Setenv("DOMAIN_ID", "0")
+	connector, err = NewConnector(participantProfile, xmlPath, WithConfigVars(nil))
+	assert.Nil(t, err)
+	assert.Nil(t, connector.Delete())
+
+	// Files on disk may include absolute paths, unlike file systems
+	appPath := filepath.Join(t.TempDir(), "App.xml")
+	assert.Nil(t, os.WriteFile(appPath, []byte(`<dds><include file="`+xmlPath+`"/></dds>`), 0644))
+	connector, err = NewConnector(participantProfile, appPath, WithConfigVars(vars))
+	assert.Nil(t, err)
+	assert.Nil(t, connector.Delete())
+	_, err = NewConnectorFS(os.DirFS(filepath.Dir(appPath)), participantProfile, "App.xml")
+	assert.NotNil(t, err)
+
+	expanded, err := expandConfigVars([]byte("$(A) ${env:TEST_INITIAL_PEER} $(B) ${env:TEST_UNDEFINED}"), map[string]string{"A": "a"})
+	assert.Nil(t, expanded)
+	assert.EqualError(t, err, "undefined config variables: $(B), ${env:TEST_UNDEFINED}")
+	expanded, err = expandConfigVars([]byte("$(A) ${env:TEST_INITIAL_PEER}"), map[string]string{"A": "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "a shmem://", string(expanded))
+
+	// Values are escaped for their context, comments and CDATA sections are
+	// left as they are
+	expanded, err = expandConfigVars([]byte(`<!-- $(B) --><e a="$(A)">$(A)<![CDATA[$(B)]]></e>`), map[string]string{"A": `"&<`})
+	assert.Nil(t, err)
+	assert.Equal(t, `<!-- $(B) --><e a="&quot;&amp;&lt;">"&amp;&lt;<![CDATA[$(B)]]></e>`, string(expanded))
+}
+
+// This test function ensures that QoS overrides are applied on top of the XML profiles
+func TestConnectorQoSOverrides(t *testing.T) {
+	_, curPath, _, _ := runtime.Caller(0)
+	xmlPath := path.Join(path.Dir(curPath), "./test/xml/Test.xml")
+
+	// Test.xml keeps all samples, the reader only keeps the last one
+	connector, err := NewConnector(participantProfile, xmlPath,
+		WithReaderQoS("MySubscriber::MyReader", qos.History(qos.KeepLast(1))))
+	assert.Nil(t, err)
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+	for _, st := range []string{"0", "1", "2"} {
+		assert.Nil(t, output.Instance.SetString("st", st))
+		assert.Nil(t, output.Write())
+	}
+	last := ""
+	for i := 0; i < 50 && last != "2"; i++ {
+		_ = connector.Wait(100)
+		assert.Nil(t, input.Read())
+		length, err := input.Samples.GetLength()
+		assert.Nil(t, err)
+		if length > 0 {
+			last, err = input.Samples.GetString(length-1, "st")
+			assert.Nil(t, err)
+		}
+	}
+	length, err := input.Samples.GetLength()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, length)
+	assert.Nil(t, connector.Delete())
+
+	// Writer and reader in different partitions do not match
+	connector, err = NewConnector(participantProfile, xmlPath, WithQoSOverrides(QoSOverrides{
+		Writers: map[string][]qos.Policy{"MyPublisher::MyWriter": {qos.Partition("tenant-a")}},
+		Readers: map[string][]qos.Policy{"MySubscriber::MyReader": {qos.Partition("tenant-b")}},
+	}))
+	assert.Nil(t, err)
+	_, err = newTestInput(connector)
+	assert.Nil(t, err)
+	output, err = newTestOutput(connector)
+	assert.Nil(t, err)
+	_, err = output.WaitForSubscriptions(500)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Nil(t, connector.Delete())
+
+	_, err = NewConnector(participantProfile, xmlPath, WithWriterQoS("MyPublisher::Unknown", qos.Reliability(qos.BestEffort)))
+	assert.ErrorIs(t, err, ErrOutputNotFound)
+}
+
+// This test function ensures that inputs and outputs can be moved to other partitions
+func TestSetPartitions(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+	partitions, err := output.Partitions()
+	assert.Nil(t, err)
+	assert.Empty(t, partitions)
+
+	otherConnector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer otherConnector.Delete()
+	otherInput, err := newTestInput(otherConnector)
+	assert.Nil(t, err)
+
+	assert.Nil(t, input.SetPartitions([]string{"tenant-a"}))
+	assert.Nil(t, otherInput.SetPartitions([]string{"tenant-b"}))
+	assert.Nil(t, output.SetPartitions([]string{"tenant-a"}))
+	partitions, err = output.Partitions()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tenant-a"}, partitions)
+	partitions, err = otherInput.Partitions()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tenant-b"}, partitions)
+
+	// Only the input in the partition of the output receives its samples
+	_, err = output.WaitForSubscriptions(2000)
+	assert.Nil(t, err)
+	assert.Nil(t, output.Instance.SetString("st", "tenant-a"))
+	assert.Nil(t, output.Write())
+	assert.Nil(t, connector.Wait(2000))
+	assert.Nil(t, input.Take())
+	st, err := input.Samples.GetString(0, "st")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant-a", st)
+	assert.ErrorIs(t, otherConnector.Wait(500), ErrTimeout)
+
+	// Back to the default partition
+	assert.Nil(t, otherInput.SetPartitions(nil))
+	partitions, err = otherInput.Partitions()
+	assert.Nil(t, err)
+	assert.Empty(t, partitions)
+
+	// The samples queued by ReadWith are kept
+	assert.Nil(t, output.Instance.SetString("st", "queued"))
+	assert.Nil(t, output.Write())
+	var queued []SelectedSample
+	for i := 0; i < 20 && len(queued) == 0; i++ {
+		_ = input.Wait(100)
+		queued, err = input.ReadWith(Selector{})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, input.SetPartitions([]string{"tenant-a"}))
+	queued, err = input.ReadWith(Selector{})
+	assert.Nil(t, err)
+	assert.Len(t, queued, 1)
+
+	var nullOutput *Output
+	assert.NotNil(t, nullOutput.SetPartitions(nil))
+	_, err = nullOutput.Partitions()
+	assert.NotNil(t, err)
+}
+
+// Tests that the participant is not created again while goroutines use it
+func TestSetPartitionsWatched(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := input.MatchEvents(ctx)
+	assert.ErrorIs(t, input.SetPartitions([]string{"tenant-a"}), ErrConnectorInUse)
+	cancel()
+	for range events {
+	}
+	assert.Nil(t, input.SetPartitions([]string{"tenant-a"}))
+
+	release, err := connector.Watch()
+	assert.Nil(t, err)
+	assert.ErrorIs(t, input.SetPartitions(nil), ErrConnectorInUse)
+	release()
+	release()
+	assert.Nil(t, input.SetPartitions(nil))
+
+	var nullConnector *Connector
+	_, err = nullConnector.Watch()
+	assert.NotNil(t, err)
+}
+
+// This test function ensures that content filters are set and changed at runtime
+func TestNewFilteredInput(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := connector.NewFilteredInput("MySubscriber::MyReader", "l > %0", []string{"10"})
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	// Only the samples passing the filter are received
+	_, err = output.WaitForSubscriptions(2000)
+	assert.Nil(t, err)
+	for _, l := range []int32{5, 20} {
+		assert.Nil(t, output.Instance.SetInt32("l", l))
+		assert.Nil(t, output.Write())
+	}
+	assert.Nil(t, connector.Wait(2000))
+	assert.Nil(t, input.Take())
+	length, err := input.Samples.GetLength()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, length)
+	l, err := input.Samples.GetInt32(0, "l")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(20), l)
+
+	release, err := connector.Watch()
+	assert.Nil(t, err)
+	assert.ErrorIs(t, input.SetFilterParameters([]string{"30"}), ErrConnectorInUse)
+	_, err = connector.NewFilteredInput("MySubscriber::MyReader", "l > 0", nil)
+	assert.ErrorIs(t, err, ErrConnectorInUse)
+	release()
+
+	assert.Nil(t, input.SetFilterParameters([]string{"30"}))
+	_, err = output.WaitForSubscriptions(2000)
+	assert.Nil(t, err)
+	assert.Nil(t, output.Instance.SetInt32("l", 25))
+	assert.Nil(t, output.Write())
+	assert.ErrorIs(t, connector.Wait(500), ErrTimeout)
+
+	_, err = connector.NewFilteredInput("MySubscriber::Invalid", "l > 0", nil)
+	assert.ErrorIs(t, err, ErrInputNotFound)
+	var nullConnector *Connector
+	_, err = nullConnector.NewFilteredInput("MySubscriber::MyReader", "l > 0", nil)
+	assert.NotNil(t, err)
+}
+
+// This test function ensures that content filters are given to NewConnector
+func TestConnectorContentFilter(t *testing.T) {
+	_, curPath, _, _ := runtime.Caller(0)
+	xmlPath := path.Join(path.Dir(curPath), "./test/xml/Test.xml")
+
+	connector, err := NewConnector(participantProfile, xmlPath,
+		WithContentFilter("MySubscriber::MyReader", "st = %0", []string{"'BLUE'"}))
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	_, err = output.WaitForSubscriptions(2000)
+	assert.Nil(t, err)
+	for _, st := range []string{"RED", "BLUE"} {
+		assert.Nil(t, output.Instance.SetString("st", st))
+		assert.Nil(t, output.Write())
+	}
+	assert.Nil(t, connector.Wait(2000))
+	assert.Nil(t, input.Take())
+	st, err := input.Samples.GetString(0, "st")
+	assert.Nil(t, err)
+	assert.Equal(t, "BLUE", st)
+
+	otherConnector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer otherConnector.Delete()
+	otherInput, err := newTestInput(otherConnector)
+	assert.Nil(t, err)
+	assert.NotNil(t, otherInput.SetFilterParameters([]string{"0"}))
+
+	var nullInput *Input
+	assert.NotNil(t, nullInput.SetFilterParameters(nil))
+}
+
+// This test function ensures that the versions of the native libraries are reported
+func TestNativeVersion(t *testing.T) {
+	version, err := NativeVersion()
+	assert.Nil(t, err)
+	assert.Regexp(t, `^[0-9]+(\.[0-9]+)+$`, version.Core)
+	assert.Regexp(t, `^[0-9]+(\.[0-9]+)+$`, version.Connector)
+	assert.Contains(t, version.ConnectorBuild, version.Connector)
+	assert.Contains(t, version.String(), version.Connector)
+}
+
 // This test function ensures that an error is raised if an incorrect xml path is passed to the Connector constructor.
 func TestInvalidXMLPath(t *testing.T) {
 	invalidXMLPath := "invalid/path/to/xml"
 
 	connector, err := NewConnector(participantProfile, invalidXMLPath)
 	assert.Nil(t, connector)
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrConfigNotFound)
+	assert.Contains(t, err.Error(), invalidXMLPath)
 }
 
 // This test function ensures that an error is raised if an invalid participant profile name is passed to the Connector constructor.
@@ -49,7 +474,13 @@ func TestInvalidParticipantProfile(t *testing.T) {
 
 	connector, err := NewConnector(invalidParticipantProfile, xmlPath)
 	assert.Nil(t, connector)
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrParticipantNotFound)
+	assert.Contains(t, err.Error(), participantProfile)
+
+	var connectorErr *ConnectorError
+	assert.True(t, errors.As(err, &connectorErr))
+	assert.Equal(t, invalidParticipantProfile, connectorErr.ConfigName)
+	assert.Equal(t, xmlPath, connectorErr.URL)
 }
 
 // This test function ensures that an error is raised if an invalid xml file is passed to the Connector constructor.
@@ -59,7 +490,8 @@ func TestInvalidXMLProfile(t *testing.T) {
 
 	connector, err := NewConnector(participantProfile, xmlPath)
 	assert.Nil(t, connector)
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrInvalidXML)
+	assert.Contains(t, err.Error(), "line 63")
 }
 
 // This function tests the correct instantiation of Connector object.
@@ -127,7 +559,8 @@ func TestInvalidDR(t *testing.T) {
 	assert.NotNil(t, connector)
 	input, err := connector.GetInput(invalidReaderName)
 	assert.Nil(t, input)
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrInputNotFound)
+	assert.Contains(t, err.Error(), "available: MySubscriber::MyReader")
 }
 
 func TestCreateDR(t *testing.T) {
@@ -158,7 +591,8 @@ func TestInvalidWriter(t *testing.T) {
 	defer connector.Delete()
 	output, err := connector.GetOutput(invalidWriterName)
 	assert.Nil(t, output)
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrOutputNotFound)
+	assert.Contains(t, err.Error(), "available: MyPublisher::MyWriter")
 }
 
 func TestCreateWriter(t *testing.T) {
@@ -411,3 +845,676 @@ func TestSimpleMatching(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotEmpty(t, matches)
 }
+
+// Tests that matched endpoints are returned as Go values using the two
+// participants defined in TestConnector1.xml and TestConnector2.xml
+func TestMatchedEndpoints(t *testing.T) {
+	_, curPath, _, _ := runtime.Caller(0)
+	xmlPath1 := path.Join(path.Dir(curPath), "./test/xml/TestConnector1.xml")
+	xmlPath2 := path.Join(path.Dir(curPath), "./test/xml/TestConnector2.xml")
+
+	subConnector, err := NewConnector("MyParticipantLibrary::Zero", xmlPath1)
+	assert.Nil(t, err)
+	defer subConnector.Delete()
+	pubConnector, err := NewConnector("MyParticipantLibrary2::MyParticipant2", xmlPath1+";"+xmlPath2)
+	assert.Nil(t, err)
+	defer pubConnector.Delete()
+
+	input, err := subConnector.GetInput("MySubscriber::MySquareReader")
+	assert.Nil(t, err)
+	output, err := pubConnector.GetOutput("MyPublisher2::MySquareWriter2")
+	assert.Nil(t, err)
+
+	// Both participants also contain a matching endpoint of their own, so wait
+	// until the remote one has been discovered as well
+	for i := 0; i < 20; i++ {
+		count, err := input.MatchedPublicationCount()
+		assert.Nil(t, err)
+		if count >= 2 {
+			break
+		}
+		_, _ = input.WaitForPublications(500)
+	}
+
+	publications, err := input.MatchedPublications()
+	assert.Nil(t, err)
+	var names []string
+	for _, publication := range publications {
+		names = append(names, publication.Name)
+	}
+	assert.Contains(t, names, "MySquareWriter")
+	assert.Contains(t, names, "MySquareWriter2")
+
+	for i := 0; i < 20; i++ {
+		count, err := output.MatchedSubscriptionCount()
+		assert.Nil(t, err)
+		if count >= 1 {
+			break
+		}
+		_, _ = output.WaitForSubscriptions(500)
+	}
+
+	subscriptions, err := output.MatchedSubscriptions()
+	assert.Nil(t, err)
+	names = nil
+	for _, subscription := range subscriptions {
+		names = append(names, subscription.Name)
+	}
+	assert.Contains(t, names, "MySquareReader")
+
+	count, err := output.MatchedSubscriptionCount()
+	assert.Nil(t, err)
+	assert.Equal(t, len(subscriptions), count)
+}
+
+func TestParseMatchedEndpoints(t *testing.T) {
+	endpoints, err := parseMatchedEndpoints(`[{"name":"MyWriter"},{"name":null,"reliability":"RELIABLE"}]`)
+	assert.Nil(t, err)
+	assert.Len(t, endpoints, 2)
+	assert.Equal(t, "MyWriter", endpoints[0].Name)
+	assert.Nil(t, endpoints[0].QoS)
+	assert.Equal(t, "", endpoints[1].Name)
+	assert.Equal(t, "RELIABLE", endpoints[1].QoS["reliability"])
+
+	endpoints, err = parseMatchedEndpoints("")
+	assert.Nil(t, err)
+	assert.Empty(t, endpoints)
+
+	_, err = parseMatchedEndpoints("not json")
+	assert.NotNil(t, err)
+}
+
+func TestDiffMatchedEndpoints(t *testing.T) {
+	writer := MatchedEndpoint{Name: "MyWriter"}
+	unnamed := MatchedEndpoint{}
+
+	events := diffMatchedEndpoints(nil, []MatchedEndpoint{writer, unnamed, unnamed})
+	assert.Equal(t, []MatchEvent{
+		{Kind: Matched, Endpoint: writer},
+		{Kind: Matched, Endpoint: unnamed},
+		{Kind: Matched, Endpoint: unnamed},
+	}, events)
+
+	events = diffMatchedEndpoints([]MatchedEndpoint{writer, unnamed, unnamed}, []MatchedEndpoint{unnamed})
+	assert.Equal(t, []MatchEvent{
+		{Kind: Unmatched, Endpoint: writer},
+		{Kind: Unmatched, Endpoint: unnamed},
+	}, events)
+
+	assert.Empty(t, diffMatchedEndpoints([]MatchedEndpoint{writer}, []MatchedEndpoint{writer}))
+	assert.Equal(t, "Matched", Matched.String())
+	assert.Equal(t, "Unmatched", Unmatched.String())
+}
+
+// Tests that a writer joining and leaving is reported by Input.MatchEvents
+func TestMatchEvents(t *testing.T) {
+	_, curPath, _, _ := runtime.Caller(0)
+	xmlPath := path.Join(path.Dir(curPath), "./test/xml/TestConnector1.xml")
+
+	subConnector, err := NewConnector("MyParticipantLibrary::DiscoveryTestReaderOnly", xmlPath)
+	assert.Nil(t, err)
+	defer subConnector.Delete()
+	input, err := subConnector.GetInput("TestSubscriber::TestReader")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := input.MatchEvents(ctx)
+	// The channel must be closed before the connector is deleted
+	defer func() {
+		cancel()
+		for range events {
+		}
+	}()
+
+	pubConnector, err := NewConnector("MyParticipantLibrary::DiscoveryTestWriterOnly", xmlPath)
+	assert.Nil(t, err)
+	_, err = pubConnector.GetOutput("TestPublisher::TestWriter")
+	assert.Nil(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, Matched, event.Kind)
+		assert.Equal(t, "TestWriter", event.Endpoint.Name)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Matched event")
+	}
+
+	assert.Nil(t, pubConnector.Delete())
+
+	select {
+	case event := <-events:
+		assert.Equal(t, Unmatched, event.Kind)
+		assert.Equal(t, "TestWriter", event.Endpoint.Name)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Unmatched event")
+	}
+}
+
+func TestIdentityJSON(t *testing.T) {
+	id := Identity{
+		WriterGUID:     [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SequenceNumber: 7,
+	}
+	jsonData, err := json.Marshal(id)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"writer_guid":[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16],"sequence_number":7}`, string(jsonData))
+
+	var decoded Identity
+	assert.Nil(t, json.Unmarshal(jsonData, &decoded))
+	assert.Equal(t, id, decoded)
+}
+
+// Tests that the source timestamp and identity set with WriteAt and
+// WriteWithIdentity are received by the Input
+func TestWriteAtAndWithIdentity(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	sourceTime := time.Unix(1700000000, 123456789)
+	assert.Nil(t, output.Instance.SetString("st", "write_at"))
+	assert.Nil(t, output.WriteAt(sourceTime))
+	assert.Nil(t, connector.Wait(-1))
+	assert.Nil(t, input.Take())
+
+	ts, err := input.Infos.GetSourceTimestamp(0)
+	assert.Nil(t, err)
+	assert.Equal(t, sourceTime.UnixNano(), ts)
+
+	id := Identity{
+		WriterGUID:     [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SequenceNumber: 42,
+	}
+	assert.Nil(t, output.Instance.SetString("st", "write_with_identity"))
+	assert.Nil(t, output.WriteWithIdentity(id))
+	assert.Nil(t, connector.Wait(-1))
+	assert.Nil(t, input.Take())
+
+	receivedID, err := input.Infos.GetIdentity(0)
+	assert.Nil(t, err)
+	assert.Equal(t, id, receivedID)
+
+	assert.NotNil(t, output.WriteAt(time.Unix(-1, 0)))
+
+	var nullOutput *Output
+	assert.NotNil(t, nullOutput.WriteAt(sourceTime))
+	assert.NotNil(t, nullOutput.WriteWithIdentity(id))
+}
+
+// Tests the iterators over the samples and infos of an input
+func TestIterators(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	for _, st := range []string{"first", "second"} {
+		assert.Nil(t, output.Instance.SetString("st", st))
+		assert.Nil(t, output.Instance.SetInt32("l", 42))
+		assert.Nil(t, output.Write())
+	}
+	// Disposing an instance produces a sample without valid data
+	assert.Nil(t, output.Instance.SetString("st", "first"))
+	assert.Nil(t, output.WriteWithParams(`{"action":"dispose"}`))
+
+	received := 0
+	for received < 3 {
+		assert.Nil(t, connector.Wait(2000))
+		assert.Nil(t, input.Read())
+		received, err = input.Samples.GetLength()
+		assert.Nil(t, err)
+	}
+
+	var all []int
+	for sample := range input.Samples.All() {
+		all = append(all, sample.Index())
+	}
+	assert.Nil(t, input.Samples.Err())
+	assert.Equal(t, []int{0, 1, 2}, all)
+
+	var valid []string
+	for sample := range input.ValidSamples() {
+		st, err := sample.GetString("st")
+		assert.Nil(t, err)
+		valid = append(valid, st)
+
+		l, err := sample.GetInt("l")
+		assert.Nil(t, err)
+		assert.Equal(t, 42, l)
+
+		var data types.Test
+		assert.Nil(t, sample.Get(&data))
+		assert.Equal(t, st, data.St)
+	}
+	assert.Nil(t, input.Samples.Err())
+	assert.ElementsMatch(t, []string{"first", "second"}, valid)
+
+	var invalid []int
+	for i, info := range input.Infos.All() {
+		assert.Greater(t, info.ReceptionTimestamp, int64(0))
+		if !info.Valid {
+			invalid = append(invalid, i)
+			assert.Equal(t, "NOT_ALIVE_DISPOSED", info.InstanceState)
+		}
+	}
+	assert.Nil(t, input.Infos.Err())
+	assert.Len(t, invalid, 1)
+
+	// Breaking out of the loop stops the iteration
+	count := 0
+	for range input.Samples.All() {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+
+	var nullSamples *Samples
+	for range nullSamples.All() {
+		t.Fatal("a null Samples must not yield")
+	}
+	assert.NotNil(t, nullSamples.Err())
+}
+
+// filterSample is a sample read by filter expressions in TestFilter
+type filterSample map[string]interface{}
+
+func (sample filterSample) GetString(fieldName string) (string, error) {
+	value, ok := sample[fieldName].(string)
+	if !ok {
+		return "", errors.New("not a string")
+	}
+	return value, nil
+}
+
+func (sample filterSample) GetFloat64(fieldName string) (float64, error) {
+	value, ok := sample[fieldName].(float64)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+	return value, nil
+}
+
+func (sample filterSample) GetBoolean(fieldName string) (bool, error) {
+	value, ok := sample[fieldName].(bool)
+	if !ok {
+		return false, errors.New("not a boolean")
+	}
+	return value, nil
+}
+
+// This test function ensures that filter expressions are parsed and evaluated
+func TestFilter(t *testing.T) {
+	sample := filterSample{"x": 20.0, "color": "BLUE", "visible": true, "position.y": -1.5}
+	for expression, expected := range map[string]bool{
+		"x > 10 && color =~ '^B'":               true,
+		"x > 30":                                false,
+		`!(x >= 30) || color == "RED"`:          true,
+		"visible":                               true,
+		"visible == false":                      false,
+		"position.y < -1":                       true,
+		"position.y>-1.6e0":                     true,
+		"missing > 1":                           false,
+		"color !~ 'R'":                          true,
+		"x < 1 || (color == 'BLUE' && visible)": true,
+		`color == 'it\'s'`:                      false,
+	} {
+		filter, err := compileFilter(expression)
+		assert.Nil(t, err, expression)
+		assert.Equal(t, expected, filter(sample), expression)
+	}
+
+	for _, expression := range []string{"x >", "x > 'a", "(x > 1", "x =~ 1", "visible < true", "x > 1 y", "x # 1", "x =~ '['", "'a' == x"} {
+		_, err := Filter(expression)
+		assert.NotNil(t, err, expression)
+	}
+	assert.Panics(t, func() { MustFilter("x >") })
+}
+
+// This test function ensures that queries select and project samples
+func TestQuery(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	for i, st := range []string{"BLUE", "RED", "BLACK"} {
+		// 2^53 + 1 cannot be represented by a double
+		assert.Nil(t, output.Instance.SetJSON([]byte(`{"ll": 9007199254740993}`)))
+		assert.Nil(t, output.Instance.SetString("st", st))
+		assert.Nil(t, output.Instance.SetInt32("l", int32(10*i)))
+		assert.Nil(t, output.Instance.SetFloat64("d", 1.5))
+		assert.Nil(t, output.Write())
+	}
+	received := 0
+	for received < 3 {
+		assert.Nil(t, connector.Wait(2000))
+		assert.Nil(t, input.Read())
+		received, err = input.Samples.GetLength()
+		assert.Nil(t, err)
+	}
+
+	var selected []string
+	for sample := range input.Where(MustFilter("st =~ '^B'")).Where(func(sample SampleView) bool {
+		l, err := sample.GetInt("l")
+		return err == nil && l > 0
+	}).All() {
+		st, err := sample.GetString("st")
+		assert.Nil(t, err)
+		selected = append(selected, st)
+	}
+	assert.Nil(t, input.Samples.Err())
+	assert.Equal(t, []string{"BLACK"}, selected)
+
+	// Only the selected members are retrieved
+	for sample := range input.Select("st", "l", "ll").All() {
+		var data types.Test
+		assert.Nil(t, sample.Get(&data))
+		assert.NotEmpty(t, data.St)
+		assert.Equal(t, int64(1<<53+1), data.Ll)
+		assert.Zero(t, data.D)
+
+		var notStruct map[string]interface{}
+		assert.NotNil(t, sample.Get(&notStruct))
+	}
+	for sample := range input.Select("unknown").All() {
+		var data types.Test
+		assert.NotNil(t, sample.Get(&data))
+	}
+
+	var nullInput *Input
+	for range nullInput.Where(MustFilter("l > 0")).All() {
+		t.Fatal("a null Input must not yield")
+	}
+}
+
+// This test function ensures that the last value cache follows the instances
+func TestLastValueCache(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	cache, err := NewLastValueCache[types.Test](input)
+	assert.Nil(t, err)
+	assert.Len(t, cache.keys, 1)
+	assert.Equal(t, "st", cache.keys[0].Name)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := cache.Changes(ctx)
+	var received []CacheChange[types.Test]
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for change := range changes {
+			received = append(received, change)
+		}
+	}()
+
+	for _, sample := range []types.Test{{St: "a", L: 1}, {St: "b", L: 2}, {St: "a", L: 3}} {
+		assert.Nil(t, output.Instance.Set(&sample))
+		assert.Nil(t, output.Write())
+	}
+	for i := 0; i < 20 && (len(cache.Snapshot()) < 2 || cache.Snapshot()["a"].L != 3); i++ {
+		_ = input.Wait(100)
+		assert.Nil(t, cache.Update())
+	}
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, int32(3), value.L)
+	_, ok = cache.Get("c")
+	assert.False(t, ok)
+
+	// Disposed instances are removed
+	assert.Nil(t, output.Instance.SetString("st", "a"))
+	assert.Nil(t, output.WriteWithParams(`{"action":"dispose"}`))
+	for i := 0; i < 20; i++ {
+		if _, ok := cache.Get("a"); !ok {
+			break
+		}
+		_ = input.Wait(100)
+		assert.Nil(t, cache.Update())
+	}
+	assert.Equal(t, []string{"b"}, slices.Collect(maps.Keys(cache.Snapshot())))
+
+	cancel()
+	<-done
+	assert.Equal(t, CacheChange[types.Test]{Key: "a", Value: types.Test{St: "a", L: 3}, Removed: true}, received[len(received)-1])
+
+	_, err = NewLastValueCache[types.Test](nil)
+	assert.NotNil(t, err)
+	var nullCache *LastValueCache[types.Test]
+	assert.NotNil(t, nullCache.Update())
+	assert.Nil(t, nullCache.Snapshot())
+
+	// Key values containing separators do not collide
+	assert.Equal(t, "a", cacheKey([]interface{}{"a"}))
+	assert.Equal(t, cacheKey([]interface{}{1, "b"}), cacheKey([]interface{}{json.Number("1"), "b"}))
+	assert.NotEqual(t, cacheKey([]interface{}{"a/b", "c"}), cacheKey([]interface{}{"a", "b/c"}))
+	assert.NotEqual(t, cacheKey([]interface{}{`a","b`, "c"}), cacheKey([]interface{}{"a", `b","c`}))
+}
+
+// This test function ensures that instance events follow the lifecycle of instances
+func TestInstanceEvents(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := input.InstanceEvents(ctx)
+	assert.Nil(t, err)
+
+	// Only the goroutine uses the input. The output is used meanwhile, its
+	// calls being serialized with the ones of the goroutine by the connector.
+	assert.Nil(t, output.Instance.SetString("st", "a"))
+	for _, l := range []int32{1, 2} {
+		assert.Nil(t, output.Instance.SetInt32("l", l))
+		assert.Nil(t, output.Write())
+	}
+	assert.Nil(t, output.WriteWithParams(`{"action":"dispose"}`))
+
+	var kinds []InstanceEventKind
+	for event := range events {
+		assert.Equal(t, map[string]interface{}{"st": "a"}, event.Key)
+		kinds = append(kinds, event.Kind)
+		if event.Kind == InstanceDisposed {
+			assert.Equal(t, "NOT_ALIVE_DISPOSED", event.Info.InstanceState)
+			cancel()
+		}
+	}
+	assert.Equal(t, []InstanceEventKind{InstanceNew, InstanceUpdated, InstanceDisposed}, kinds)
+	assert.Equal(t, "NoWriters", InstanceNoWriters.String())
+
+	var nullInput *Input
+	_, err = nullInput.InstanceEvents(context.Background())
+	assert.NotNil(t, err)
+	unknownInput := &Input{connector: connector, name: "MySubscriber::Unknown"}
+	_, err = unknownInput.InstanceEvents(context.Background())
+	assert.NotNil(t, err)
+}
+
+// This test function ensures that selectors emulate the sample, view and instance states
+func TestReadWith(t *testing.T) {
+	connector, err := newTestConnector()
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	write := func(samples ...types.Test) {
+		for _, sample := range samples {
+			assert.Nil(t, output.Instance.Set(&sample))
+			assert.Nil(t, output.Write())
+		}
+	}
+	// readAll reads the samples in any state until count samples are queued
+	readAll := func(count int) []SelectedSample {
+		var samples []SelectedSample
+		for i := 0; i < 20 && len(samples) < count; i++ {
+			_ = input.Wait(100)
+			samples, err = input.ReadWith(Selector{})
+			assert.Nil(t, err)
+		}
+		assert.Len(t, samples, count)
+		return samples
+	}
+	notRead := Selector{SampleStates: NotReadSampleState}
+
+	write(types.Test{St: "a", L: 1}, types.Test{St: "b", L: 2}, types.Test{St: "a", L: 3})
+	samples, err := input.ReadWith(notRead)
+	assert.Nil(t, err)
+	for len(samples) < 3 {
+		assert.Nil(t, input.Wait(2000))
+		more, err := input.ReadWith(notRead)
+		assert.Nil(t, err)
+		samples = append(samples, more...)
+	}
+	assert.Equal(t, "NOT_READ", samples[0].Info.SampleState)
+	assert.Equal(t, "NEW", samples[0].Info.ViewState)
+	samples, err = input.ReadWith(notRead)
+	assert.Nil(t, err)
+	assert.Empty(t, samples)
+
+	// Selection of an instance, of a number of samples and of new instances
+	samples, err = input.ReadWith(Selector{Instance: []interface{}{"b"}})
+	assert.Nil(t, err)
+	assert.Len(t, samples, 1)
+	assert.Equal(t, "READ", samples[0].Info.SampleState)
+	assert.Equal(t, "NOT_NEW", samples[0].Info.ViewState)
+	var data types.Test
+	assert.Nil(t, samples[0].Get(&data))
+	assert.Equal(t, int32(2), data.L)
+	samples, err = input.ReadWith(Selector{MaxSamples: 2})
+	assert.Nil(t, err)
+	assert.Len(t, samples, 2)
+	write(types.Test{St: "c", L: 4})
+	readAll(4)
+	write(types.Test{St: "d", L: 5})
+	newInstances := Selector{ViewStates: NewViewState}
+	for i := 0; i < 20 && len(samples) != 1; i++ {
+		_ = input.Wait(100)
+		samples, err = input.ReadWith(newInstances)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, samples, 1)
+	assert.Nil(t, samples[0].Get(&data))
+	assert.Equal(t, "d", data.St)
+	samples, err = input.ReadWith(newInstances)
+	assert.Nil(t, err)
+	assert.Empty(t, samples)
+
+	// One instance at a time
+	samples, err = input.TakeNextInstance(Selector{})
+	assert.Nil(t, err)
+	assert.Len(t, samples, 2)
+	for _, sample := range samples {
+		assert.Nil(t, sample.Get(&data))
+		assert.Equal(t, "a", data.St)
+	}
+	readAll(3)
+
+	// Disposed instances
+	assert.Nil(t, output.Instance.SetString("st", "b"))
+	assert.Nil(t, output.WriteWithParams(`{"action":"dispose"}`))
+	readAll(4)
+	samples, err = input.TakeWith(Selector{InstanceStates: NotAliveDisposedInstanceState})
+	assert.Nil(t, err)
+	assert.Len(t, samples, 2)
+	assert.Equal(t, "NOT_ALIVE_DISPOSED", samples[1].Info.InstanceState)
+	assert.False(t, samples[1].Info.Valid)
+	samples, err = input.TakeWith(Selector{})
+	assert.Nil(t, err)
+	assert.Len(t, samples, 2)
+	readAll(0)
+
+	var nullInput *Input
+	_, err = nullInput.ReadWith(Selector{})
+	assert.NotNil(t, err)
+	_, err = nullInput.TakeNextInstance(Selector{})
+	assert.NotNil(t, err)
+}
+
+// This test function ensures that the samples of ReadWith are bounded by the history depth
+func TestReadWithHistoryDepth(t *testing.T) {
+	_, curPath, _, _ := runtime.Caller(0)
+	xmlPath := path.Join(path.Dir(curPath), "./test/xml/Test.xml")
+
+	connector, err := NewConnector(participantProfile, xmlPath,
+		WithReaderQoS("MySubscriber::MyReader", qos.History(qos.KeepLast(2))))
+	assert.Nil(t, err)
+	defer connector.Delete()
+	input, err := newTestInput(connector)
+	assert.Nil(t, err)
+	output, err := newTestOutput(connector)
+	assert.Nil(t, err)
+
+	// Every sample is taken from the native layer before the next one is
+	// written, so only the queue can drop them
+	var samples []SelectedSample
+	assert.Nil(t, output.Instance.SetString("st", "a"))
+	for _, l := range []int32{1, 2, 3} {
+		assert.Nil(t, output.Instance.SetInt32("l", l))
+		assert.Nil(t, output.Write())
+		samples = nil
+		for i := 0; i < 20 && (len(samples) == 0 || samples[len(samples)-1].Info.SampleState == "READ"); i++ {
+			_ = input.Wait(100)
+			samples, err = input.ReadWith(Selector{})
+			assert.Nil(t, err)
+		}
+	}
+
+	assert.Len(t, samples, 2)
+	var data types.Test
+	assert.Nil(t, samples[0].Get(&data))
+	assert.Equal(t, int32(2), data.L)
+	assert.Nil(t, samples[1].Get(&data))
+	assert.Equal(t, int32(3), data.L)
+}
+
+// This test function ensures that the history depth of readers is found in the XML profiles
+func TestReaderHistoryDepth(t *testing.T) {
+	document := `<dds>
+		<qos_library name="Lib">
+			<qos_profile name="Last"><datareader_qos><history><depth>5</depth></history></datareader_qos></qos_profile>
+			<qos_profile name="Default" is_default_qos="true"><datareader_qos><history><depth>3</depth></history></datareader_qos></qos_profile>
+		</qos_library>
+		<domain_participant_library name="MyParticipantLibrary">
+			<domain_participant name="Zero">
+				<subscriber name="MySubscriber">
+					<data_reader name="Inline" topic_ref="Test"><datareader_qos base_name="Lib::Last"><history><depth>9</depth></history></datareader_qos></data_reader>
+					<data_reader name="Base" topic_ref="Test"><datareader_qos base_name="Lib::Last"/></data_reader>
+					<data_reader name="All" topic_ref="Test"><datareader_qos><history><kind>KEEP_ALL_HISTORY_QOS</kind></history></datareader_qos></data_reader>
+					<data_reader name="Builtin" topic_ref="Test"><datareader_qos base_name="BuiltinQosLib::Generic.StrictReliable"/></data_reader>
+					<data_reader name="Default" topic_ref="Test"/>
+				</subscriber>
+			</domain_participant>
+		</domain_participant_library>
+	</dds>`
+
+	for reader, expected := range map[string]int{"Inline": 9, "Base": 5, "All": 0, "Builtin": 0, "Default": 3} {
+		depth, err := readerHistoryDepth(document, "MyParticipantLibrary::Zero", "MySubscriber::"+reader)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, depth, reader)
+	}
+	_, err := readerHistoryDepth(document, "MyParticipantLibrary::Zero", "MySubscriber::Invalid")
+	assert.ErrorIs(t, err, ErrInputNotFound)
+}
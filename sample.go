@@ -15,7 +15,15 @@ package rti
 // #include <stdlib.h>
 import "C"
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -26,6 +34,16 @@ import (
 // Samples is a sequence of data samples used by an input to read DDS data
 type Samples struct {
 	input *Input
+	err   error // error that stopped the last iteration
+}
+
+// SampleView gives access to one of the Samples of an input. It is only valid
+// until the next Read or Take on that input.
+type SampleView struct {
+	samples *Samples
+	index   int
+	// fields selected by Query.Select, retrieved by Get
+	fields []string
 }
 
 // getNumber is a function to return a number in double from a sample
@@ -33,6 +51,9 @@ func (samples *Samples) getNumber(index int, fieldName string, retVal *C.double)
 	fieldNameCStr := C.CString(fieldName)
 	defer C.free(unsafe.Pointer(fieldNameCStr))
 
+	samples.input.connector.mutex.Lock()
+	defer samples.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_number_from_sample(unsafe.Pointer(samples.input.connector.native), retVal, samples.input.nameCStr, C.int(index+1), fieldNameCStr))
 	return checkRetcode(retcode)
 }
@@ -44,6 +65,10 @@ func (samples *Samples) getNumber(index int, fieldName string, retVal *C.double)
 // GetLength is a function to get the number of samples
 func (samples *Samples) GetLength() (int, error) {
 	var retVal C.double
+
+	samples.input.connector.mutex.Lock()
+	defer samples.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_sample_count(unsafe.Pointer(samples.input.connector.native), samples.input.nameCStr, &retVal))
 	err := checkRetcode(retcode)
 	return int(retVal), err
@@ -154,6 +179,9 @@ func (samples *Samples) GetBoolean(index int, fieldName string) (bool, error) {
 
 	var retVal C.int
 
+	samples.input.connector.mutex.Lock()
+	defer samples.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_boolean_from_sample(unsafe.Pointer(samples.input.connector.native), &retVal, samples.input.nameCStr, C.int(index+1), fieldNameCStr))
 	err := checkRetcode(retcode)
 
@@ -167,6 +195,9 @@ func (samples *Samples) GetString(index int, fieldName string) (string, error) {
 
 	var retValCStr *C.char
 
+	samples.input.connector.mutex.Lock()
+	defer samples.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_string_from_sample(unsafe.Pointer(samples.input.connector.native), &retValCStr, samples.input.nameCStr, C.int(index+1), fieldNameCStr))
 	err := checkRetcode(retcode)
 	if err != nil {
@@ -183,6 +214,9 @@ func (samples *Samples) GetString(index int, fieldName string) (string, error) {
 func (samples *Samples) GetJSON(index int) ([]byte, error) {
 	var retValCStr *C.char
 
+	samples.input.connector.mutex.Lock()
+	defer samples.input.connector.mutex.Unlock()
+
 	retcode := int(C.RTI_Connector_get_json_sample(unsafe.Pointer(samples.input.connector.native), samples.input.nameCStr, C.int(index+1), &retValCStr))
 	err := checkRetcode(retcode)
 	if err != nil {
@@ -205,3 +239,216 @@ func (samples *Samples) Get(index int, v interface{}) error {
 
 	return json.Unmarshal(jsonData, &v)
 }
+
+// All returns an iterator over the samples of the last Read or Take,
+// including samples without valid data. If reading the samples fails, the
+// iteration stops and the error is returned by Err.
+func (samples *Samples) All() iter.Seq[SampleView] {
+	return func(yield func(SampleView) bool) {
+		if samples == nil || samples.input == nil || samples.input.connector == nil {
+			return
+		}
+		samples.err = nil
+
+		length, err := samples.GetLength()
+		if err != nil {
+			samples.err = err
+			return
+		}
+
+		for i := 0; i < length; i++ {
+			if !yield(SampleView{samples: samples, index: i}) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error that stopped the last iteration over All or
+// Input.ValidSamples, or nil if it completed
+func (samples *Samples) Err() error {
+	if samples == nil || samples.input == nil || samples.input.connector == nil {
+		return errors.New("samples, input, or connector is null")
+	}
+	return samples.err
+}
+
+// Index returns the position of the sample in the Samples, as used by the
+// Samples and Infos getters
+func (sample SampleView) Index() int {
+	return sample.index
+}
+
+// Get is a function to retrieve all the information of the sample and put it into an interface.
+// For samples of a Query with selected fields, only these fields are retrieved, and v must
+// be a pointer to a struct.
+func (sample SampleView) Get(v interface{}) error {
+	if len(sample.fields) > 0 {
+		return sample.getFields(v)
+	}
+	return sample.samples.Get(sample.index, v)
+}
+
+// GetJSON is a function to retrieve a slice of bytes of a JSON string from the sample
+func (sample SampleView) GetJSON() ([]byte, error) {
+	return sample.samples.GetJSON(sample.index)
+}
+
+// GetString is a function to retrieve a value of type string from the sample
+func (sample SampleView) GetString(fieldName string) (string, error) {
+	return sample.samples.GetString(sample.index, fieldName)
+}
+
+// GetBoolean is a function to retrieve a value of type boolean from the sample
+func (sample SampleView) GetBoolean(fieldName string) (bool, error) {
+	return sample.samples.GetBoolean(sample.index, fieldName)
+}
+
+// GetInt is a function to retrieve a value of type int from the sample
+func (sample SampleView) GetInt(fieldName string) (int, error) {
+	return sample.samples.GetInt(sample.index, fieldName)
+}
+
+// GetInt64 is a function to retrieve a value of type int64 from the sample
+func (sample SampleView) GetInt64(fieldName string) (int64, error) {
+	return sample.samples.GetInt64(sample.index, fieldName)
+}
+
+// GetFloat64 is a function to retrieve a value of type float64 from the sample
+func (sample SampleView) GetFloat64(fieldName string) (float64, error) {
+	return sample.samples.GetFloat64(sample.index, fieldName)
+}
+
+// getFields is a function to retrieve the selected fields of the sample, each
+// with the getter matching the kind of its field in v, and put them into v
+func (sample SampleView) getFields(v interface{}) error {
+	target := reflect.TypeOf(v)
+	if target == nil || target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("selected fields cannot be retrieved into %T, only into a pointer to a struct", v)
+	}
+
+	values := make(map[string]interface{})
+	for _, field := range sample.fields {
+		kind, err := memberKind(target.Elem(), field)
+		if err != nil {
+			return err
+		}
+
+		var value interface{}
+		switch kind {
+		case reflect.String:
+			value, err = sample.GetString(field)
+		case reflect.Bool:
+			value, err = sample.GetBoolean(field)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value, err = sample.getInteger(field)
+		case reflect.Float32, reflect.Float64:
+			value, err = sample.GetFloat64(field)
+		default:
+			return fmt.Errorf("selected field %q of %T is not a string, a number or a boolean", field, v)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Nested members are set in nested objects
+		parent := values
+		path := strings.Split(field, ".")
+		for _, name := range path[:len(path)-1] {
+			child, ok := parent[name].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				parent[name] = child
+			}
+			parent = child
+		}
+		parent[path[len(path)-1]] = value
+	}
+
+	jsonData, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// getInteger is a function to retrieve an integer field of the sample. The
+// native layer returns numbers as doubles, which cannot represent every
+// integer beyond 2^53, so larger values are read from the JSON of the sample
+// instead.
+func (sample SampleView) getInteger(field string) (json.Number, error) {
+	value, err := sample.GetFloat64(field)
+	if err != nil {
+		return "", err
+	}
+	if math.Abs(value) < 1<<53 {
+		return json.Number(strconv.FormatFloat(value, 'f', -1, 64)), nil
+	}
+
+	jsonData, err := sample.GetJSON()
+	if err != nil {
+		return "", err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var member interface{}
+	if err := decoder.Decode(&member); err != nil {
+		return "", err
+	}
+	for _, name := range strings.Split(field, ".") {
+		object, ok := member.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("field %q not found in the sample", field)
+		}
+		member = object[name]
+	}
+	number, ok := member.(json.Number)
+	if !ok {
+		return "", fmt.Errorf("field %q of the sample is not a number", field)
+	}
+	return number, nil
+}
+
+// memberKind is a function to return the kind of the field of struct type t
+// that the member path, such as "position.latitude", is decoded into
+func memberKind(t reflect.Type, path string) (reflect.Kind, error) {
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return reflect.Invalid, fmt.Errorf("selected field %q is not a member of a struct", path)
+		}
+
+		found := false
+		for i := 0; i < t.NumField() && !found; i++ {
+			field := t.Field(i)
+			jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || jsonName == "-" {
+				continue
+			}
+			if jsonName == "" {
+				jsonName = field.Name
+			}
+			// Members are matched as by encoding/json
+			if strings.EqualFold(jsonName, name) {
+				t = field.Type
+				found = true
+			}
+		}
+		if !found {
+			return reflect.Invalid, fmt.Errorf("selected field %q has no matching field in %s", path, t)
+		}
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind(), nil
+}
+
+// Info is a function to retrieve the meta data of the sample
+func (sample SampleView) Info() (SampleInfo, error) {
+	return sample.samples.input.Infos.GetInfo(sample.index)
+}
@@ -0,0 +1,361 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+// Package rti implements functions of RTI Connector for Connext DDS in Go
+package rti
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+/********
+* Types *
+*********/
+
+// SampleStateMask selects samples by whether they were already returned by
+// ReadWith
+type SampleStateMask int
+
+// ViewStateMask selects samples by whether samples of their instance were
+// already returned
+type ViewStateMask int
+
+// InstanceStateMask selects samples by the state of their instance
+type InstanceStateMask int
+
+const (
+	// ReadSampleState selects the samples already returned by ReadWith
+	ReadSampleState SampleStateMask = 1 << iota
+	// NotReadSampleState selects the samples never returned
+	NotReadSampleState
+)
+
+const (
+	// NewViewState selects the samples of instances that are new, or
+	// written again after they were disposed or lost their writers, and
+	// whose samples were never returned since
+	NewViewState ViewStateMask = 1 << iota
+	// NotNewViewState selects the samples of the other instances
+	NotNewViewState
+)
+
+const (
+	// AliveInstanceState selects the samples of alive instances
+	AliveInstanceState InstanceStateMask = 1 << iota
+	// NotAliveDisposedInstanceState selects the samples of disposed instances
+	NotAliveDisposedInstanceState
+	// NotAliveNoWritersInstanceState selects the samples of instances
+	// without writers
+	NotAliveNoWritersInstanceState
+)
+
+// Selector selects the samples returned by Input.ReadWith, Input.TakeWith
+// and Input.TakeNextInstance. Zero masks select samples in any state, and a
+// zero MaxSamples any number of samples.
+type Selector struct {
+	SampleStates   SampleStateMask
+	ViewStates     ViewStateMask
+	InstanceStates InstanceStateMask
+	MaxSamples     int
+	// Instance restricts the selection to the instance with these values of
+	// the key members of the type, in order, as in LastValueCache.Get
+	Instance []interface{}
+}
+
+// SelectedSample is a sample returned by a Selector. Unlike SampleView, it
+// remains valid after the next read or take. Info holds the states of the
+// sample when it was returned.
+type SelectedSample struct {
+	Info     SampleInfo
+	jsonData []byte
+}
+
+// sampleQueue holds the samples of an input taken from the native layer by
+// ReadWith, TakeWith and TakeNextInstance, and their states
+type sampleQueue struct {
+	keys []xmlMember
+	// depth is the maximum number of samples kept for an instance, as by
+	// the history of the reader, or 0 for no maximum
+	depth   int
+	samples []*queuedSample
+	// instances whose samples were returned, and the last state of every
+	// instance, by key. Only the instances with samples in the queue are
+	// kept.
+	viewed         map[string]bool
+	instanceStates map[string]string
+}
+
+type queuedSample struct {
+	SelectedSample
+	key  string
+	read bool
+}
+
+/*******************
+* Public Functions *
+*******************/
+
+// ReadWith is a function to return the samples of the input selected by
+// selector, without removing them, e.g. the samples not returned yet of
+// instances not seen yet:
+//
+//	samples, err := input.ReadWith(rti.Selector{
+//		SampleStates: rti.NotReadSampleState,
+//		ViewStates:   rti.NewViewState,
+//	})
+//
+// The Connector API cannot read or take some samples only, so the states
+// are emulated in Go: the samples of the input are taken from the native
+// layer into a queue of the input, from which ReadWith, TakeWith and
+// TakeNextInstance select. A sample is READ once it was returned by
+// ReadWith, and an instance is NOT_NEW once one of its samples was returned.
+// Read and Take do not see the samples of the queue, so they must not be
+// used on the same input.
+//
+// Like the reader, the queue keeps the last samples of every instance up to
+// the depth of the KEEP_LAST history of the reader in the XML configuration.
+// It is not bounded with a KEEP_ALL history, or when the history comes from
+// a profile outside of the configuration, such as a builtin one.
+func (input *Input) ReadWith(selector Selector) ([]SelectedSample, error) {
+	return input.selectSamples(selector, false, false)
+}
+
+// TakeWith is a function to return the samples of the input selected by
+// selector, removing them from the input. See ReadWith.
+func (input *Input) TakeWith(selector Selector) ([]SelectedSample, error) {
+	return input.selectSamples(selector, true, false)
+}
+
+// TakeNextInstance is a function to take the samples selected by selector
+// of a single instance, the one of the oldest selected sample, so that
+// instances can be processed one at a time. It returns no samples when no
+// sample is selected. See ReadWith.
+func (input *Input) TakeNextInstance(selector Selector) ([]SelectedSample, error) {
+	return input.selectSamples(selector, true, true)
+}
+
+// Get is a function to retrieve all the information of the sample and put it
+// into an interface
+func (sample SelectedSample) Get(v interface{}) error {
+	return json.Unmarshal(sample.jsonData, v)
+}
+
+// GetJSON is a function to retrieve a slice of bytes of a JSON string from
+// the sample
+func (sample SelectedSample) GetJSON() []byte {
+	return sample.jsonData
+}
+
+/********************
+* Private Functions *
+********************/
+
+// selectSamples is a function to return the samples of the queue of the
+// input selected by selector, removing them if take is set. With
+// nextInstance, only the samples of the instance of the first selected
+// sample are returned.
+func (input *Input) selectSamples(selector Selector, take, nextInstance bool) ([]SelectedSample, error) {
+	if input == nil {
+		return nil, errors.New("input is null")
+	}
+
+	if input.queue == nil {
+		keys, err := input.typeKeyMembers()
+		if err != nil {
+			return nil, err
+		}
+		document, err := readConfigURL(input.connector.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		depth, err := readerHistoryDepth(document, input.connector.configName, input.name)
+		if err != nil {
+			return nil, err
+		}
+		input.queue = &sampleQueue{
+			keys:           keys,
+			depth:          depth,
+			viewed:         make(map[string]bool),
+			instanceStates: make(map[string]string),
+		}
+	}
+	queue := input.queue
+	// The samples fetched before an error stay in the queue for the next
+	// call
+	if err := queue.fetch(input); err != nil {
+		return nil, err
+	}
+
+	instance, restricted := "", selector.Instance != nil
+	if restricted {
+		instance = cacheKey(selector.Instance)
+	}
+
+	var selected []SelectedSample
+	var selectedKeys []string
+	remaining := make([]*queuedSample, 0, len(queue.samples))
+	for _, sample := range queue.samples {
+		sampleState, viewState, instanceState := queue.states(sample)
+		matches := (selector.MaxSamples <= 0 || len(selected) < selector.MaxSamples) &&
+			(!restricted || sample.key == instance) &&
+			selector.matches(sampleState, viewState, instanceState)
+		if matches {
+			if nextInstance && !restricted {
+				instance, restricted = sample.key, true
+			}
+			returned := sample.SelectedSample
+			returned.Info.SampleState = "NOT_READ"
+			if sampleState == ReadSampleState {
+				returned.Info.SampleState = "READ"
+			}
+			returned.Info.ViewState = "NEW"
+			if viewState == NotNewViewState {
+				returned.Info.ViewState = "NOT_NEW"
+			}
+			returned.Info.InstanceState = queue.instanceStates[sample.key]
+			selected = append(selected, returned)
+			selectedKeys = append(selectedKeys, sample.key)
+			sample.read = true
+		}
+		if !matches || !take {
+			remaining = append(remaining, sample)
+		}
+	}
+	queue.samples = remaining
+	for _, key := range selectedKeys {
+		queue.viewed[key] = true
+	}
+	queue.prune()
+
+	return selected, nil
+}
+
+// fetch is a function to take the samples available in the native layer
+// into the queue. Samples that cannot be decoded are dropped, and the first
+// error is returned once the other samples are queued.
+func (queue *sampleQueue) fetch(input *Input) error {
+	if err := input.Take(); err != nil {
+		if err == ErrNoData {
+			return nil
+		}
+		return err
+	}
+
+	length, err := input.Samples.GetLength()
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for i := 0; i < length; i++ {
+		if err := queue.push(input, i); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	queue.trim()
+
+	return firstErr
+}
+
+// push is a function to append the sample at index of the last Take of the
+// input to the queue
+func (queue *sampleQueue) push(input *Input, index int) error {
+	info, err := input.Infos.GetInfo(index)
+	if err != nil {
+		return err
+	}
+	// The key members of samples without valid data are still set
+	jsonData, err := input.Samples.GetJSON(index)
+	if err != nil {
+		return err
+	}
+	key, err := jsonKey(jsonData, queue.keys)
+	if err != nil {
+		return err
+	}
+
+	// The native layer reports new instances, and instances written again
+	// after they were disposed or lost their writers, as NEW. A NOT_NEW
+	// instance no longer in the queue had its samples taken by TakeWith.
+	if info.ViewState == "NEW" {
+		delete(queue.viewed, key)
+	} else if _, ok := queue.instanceStates[key]; !ok {
+		queue.viewed[key] = true
+	}
+	queue.instanceStates[key] = info.InstanceState
+	queue.samples = append(queue.samples, &queuedSample{
+		SelectedSample: SelectedSample{Info: info, jsonData: jsonData},
+		key:            key,
+	})
+
+	return nil
+}
+
+// trim is a function to drop the oldest samples of the instances with more
+// samples than the depth of the queue
+func (queue *sampleQueue) trim() {
+	if queue.depth <= 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	kept := make([]*queuedSample, 0, len(queue.samples))
+	for _, sample := range slices.Backward(queue.samples) {
+		counts[sample.key]++
+		if counts[sample.key] <= queue.depth {
+			kept = append(kept, sample)
+		}
+	}
+	slices.Reverse(kept)
+	queue.samples = kept
+}
+
+// prune is a function to forget the states of the instances without samples
+// in the queue
+func (queue *sampleQueue) prune() {
+	queued := make(map[string]bool, len(queue.instanceStates))
+	for _, sample := range queue.samples {
+		queued[sample.key] = true
+	}
+	for key := range queue.instanceStates {
+		if !queued[key] {
+			delete(queue.instanceStates, key)
+			delete(queue.viewed, key)
+		}
+	}
+}
+
+// states is a function to return the current states of a sample of the queue
+func (queue *sampleQueue) states(sample *queuedSample) (SampleStateMask, ViewStateMask, InstanceStateMask) {
+	sampleState := NotReadSampleState
+	if sample.read {
+		sampleState = ReadSampleState
+	}
+	viewState := NewViewState
+	if queue.viewed[sample.key] {
+		viewState = NotNewViewState
+	}
+	instanceState := AliveInstanceState
+	switch queue.instanceStates[sample.key] {
+	case "NOT_ALIVE_DISPOSED":
+		instanceState = NotAliveDisposedInstanceState
+	case "NOT_ALIVE_NO_WRITERS":
+		instanceState = NotAliveNoWritersInstanceState
+	}
+	return sampleState, viewState, instanceState
+}
+
+// matches is a function to tell whether samples in these states are selected
+func (selector Selector) matches(sampleState SampleStateMask, viewState ViewStateMask, instanceState InstanceStateMask) bool {
+	return (selector.SampleStates == 0 || selector.SampleStates&sampleState != 0) &&
+		(selector.ViewStates == 0 || selector.ViewStates&viewState != 0) &&
+		(selector.InstanceStates == 0 || selector.InstanceStates&instanceState != 0)
+}
@@ -0,0 +1,1110 @@
+/*****************************************************************************
+*   (c) 2020 Copyright, Real-Time Innovations.  All rights reserved.         *
+*                                                                            *
+* No duplications, whole or partial, manual or electronic, may be made       *
+* without express written permission.  Any such copies, or revisions thereof,*
+* must display this notice unaltered.                                        *
+* This code contains trade secrets of Real-Time Innovations, Inc.            *
+*                                                                            *
+*****************************************************************************/
+
+package rti
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rticommunity/rticonnextdds-connector-go/config"
+	"github.com/rticommunity/rticonnextdds-connector-go/qos"
+)
+
+/********
+* Types *
+*********/
+
+// xmlMerger merges XML documents into a single <dds> document
+type xmlMerger struct {
+	buf bytes.Buffer
+	// expand, if not nil, is applied to every document before it is parsed
+	expand func(data []byte) ([]byte, error)
+	// root is the directory of the file system documents are read from, to
+	// report their paths in errors
+	root string
+}
+
+// configVariable matches the $(VAR) and ${env:VAR} references of an XML
+// configuration
+var configVariable = regexp.MustCompile(`\$\(([A-Za-z_][A-Za-z0-9_]*)\)|\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+const strURLPrefix = "str://"
+
+// textEscaper and attrEscaper escape the values of config variables in the
+// text and in the tags of an XML document
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+)
+
+// xmlConfig is the part of an XML configuration describing the participants,
+// the partitions of their publishers and subscribers and the types of their
+// readers
+type xmlConfig struct {
+	Types           []xmlModule `xml:"types"`
+	DomainLibraries []struct {
+		Name    string      `xml:"name,attr"`
+		Domains []xmlDomain `xml:"domain"`
+	} `xml:"domain_library"`
+	QoSLibraries []struct {
+		Name     string          `xml:"name,attr"`
+		Profiles []xmlQoSProfile `xml:"qos_profile"`
+	} `xml:"qos_library"`
+	ParticipantLibraries []struct {
+		Name         string           `xml:"name,attr"`
+		Participants []xmlParticipant `xml:"domain_participant"`
+	} `xml:"domain_participant_library"`
+}
+
+type xmlParticipant struct {
+	Name        string          `xml:"name,attr"`
+	DomainRef   string          `xml:"domain_ref,attr"`
+	Publishers  []xmlEntityList `xml:"publisher"`
+	Subscribers []xmlEntityList `xml:"subscriber"`
+	xmlTopics
+}
+
+type xmlDomain struct {
+	Name     string `xml:"name,attr"`
+	BaseName string `xml:"base_name,attr"`
+	xmlTopics
+}
+
+// xmlTopics are the registered types and the topics of a domain or a
+// participant
+type xmlTopics struct {
+	RegisterTypes []struct {
+		Name    string `xml:"name,attr"`
+		TypeRef string `xml:"type_ref,attr"`
+	} `xml:"register_type"`
+	Topics []struct {
+		Name            string `xml:"name,attr"`
+		RegisterTypeRef string `xml:"register_type_ref,attr"`
+	} `xml:"topic"`
+}
+
+// xmlModule is a <types> element or a module of types
+type xmlModule struct {
+	Name    string      `xml:"name,attr"`
+	Modules []xmlModule `xml:"module"`
+	Structs []struct {
+		Name     string      `xml:"name,attr"`
+		BaseType string      `xml:"baseType,attr"`
+		Members  []xmlMember `xml:"member"`
+	} `xml:"struct"`
+}
+
+// xmlMember is a member of a struct, e.g. <member name="x" type="int32"/>
+type xmlMember struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+	Key  bool   `xml:"key,attr"`
+}
+
+type xmlQoSProfile struct {
+	Name          string   `xml:"name,attr"`
+	BaseName      string   `xml:"base_name,attr"`
+	IsDefaultQoS  bool     `xml:"is_default_qos,attr"`
+	PublisherQoS  []xmlQoS `xml:"publisher_qos"`
+	SubscriberQoS []xmlQoS `xml:"subscriber_qos"`
+	DataReaderQoS []xmlQoS `xml:"datareader_qos"`
+}
+
+// xmlQoS is the QoS of a publisher, a subscriber or a data reader
+type xmlQoS struct {
+	BaseName   string `xml:"base_name,attr"`
+	Partitions []struct {
+		Names []string `xml:"name>element"`
+	} `xml:"partition"`
+	Histories []config.History `xml:"history"`
+}
+
+// xmlEntityList is a publisher or a subscriber
+type xmlEntityList struct {
+	Name          string   `xml:"name,attr"`
+	PublisherQoS  []xmlQoS `xml:"publisher_qos"`
+	SubscriberQoS []xmlQoS `xml:"subscriber_qos"`
+	Writers       []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"data_writer"`
+	Readers []struct {
+		Name     string   `xml:"name,attr"`
+		TopicRef string   `xml:"topic_ref,attr"`
+		QoS      []xmlQoS `xml:"datareader_qos"`
+	} `xml:"data_reader"`
+}
+
+// xmlContentFilter is the content filter of a data reader
+type xmlContentFilter struct {
+	XMLName    xml.Name `xml:"content_filter"`
+	Name       string   `xml:"name,attr"`
+	Kind       string   `xml:"kind,attr"`
+	Expression string   `xml:"expression"`
+	Parameters *struct {
+		Elements []string `xml:"element"`
+	} `xml:"expression_parameters,omitempty"`
+}
+
+/********************
+* Private Functions *
+********************/
+
+// readXMLFS is a function to merge XML documents of a file system into a
+// single <dds> document.
+//
+// The content of the root <dds> element of each document is concatenated in
+// order. <include file="..."/> elements are replaced by the content of the
+// included document, resolved relative to the including one. XML
+// declarations and comments are dropped, so that the result can be passed
+// in a str:// URL.
+func readXMLFS(fsys fs.FS, paths ...string) (string, error) {
+	if fsys == nil {
+		return "", errors.New("file system is null")
+	}
+	if len(paths) == 0 {
+		return "", errors.New("no XML file given")
+	}
+
+	merger := new(xmlMerger)
+	merger.buf.WriteString("<dds>")
+	for _, p := range paths {
+		if err := merger.readFile(fsys, path.Clean(p), nil); err != nil {
+			return "", err
+		}
+	}
+	merger.buf.WriteString("</dds>")
+
+	return merger.buf.String(), nil
+}
+
+// preprocessConfigURL is a function to apply the options of NewConnector to
+// the XML documents of url, and return them as a str:// URL.
+//
+// Files, separated by semicolons, are merged as by readXMLFS, so that their
+// includes are pre-processed too.
+func preprocessConfigURL(configName, url string, options connectorOptions) (string, error) {
+	var expand func(data []byte) ([]byte, error)
+	if options.expandVars {
+		expand = func(data []byte) ([]byte, error) {
+			return expandConfigVars(data, options.vars)
+		}
+	}
+
+	document, err := readConfigURL(url, expand)
+	if err != nil {
+		var syntaxErr *xml.SyntaxError
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return "", &ConnectorError{Kind: ErrConfigNotFound, ConfigName: configName, URL: url, Cause: err}
+		case errors.As(err, &syntaxErr):
+			return "", &ConnectorError{Kind: ErrInvalidXML, ConfigName: configName, URL: url, Cause: err}
+		}
+		return "", err
+	}
+
+	if options.rewritesXML() {
+		if document, err = applyOverrides(document, configName, options); err != nil {
+			return "", err
+		}
+	}
+
+	return strURLPrefix + `"` + document + `"`, nil
+}
+
+// readConfigURL is a function to read the XML documents of a NewConnector
+// url, applying expand to them if it is not nil. Files, separated by
+// semicolons, are merged as by readXMLFS.
+func readConfigURL(url string, expand func(data []byte) ([]byte, error)) (string, error) {
+	if content, ok := strings.CutPrefix(url, strURLPrefix); ok {
+		content = strings.TrimSpace(content)
+		if len(content) < 2 || content[0] != '"' || content[len(content)-1] != '"' {
+			return "", errors.New("str:// URL content is not quoted")
+		}
+		document := []byte(content[1 : len(content)-1])
+		if expand != nil {
+			var err error
+			if document, err = expand(document); err != nil {
+				return "", err
+			}
+		}
+		return string(document), nil
+	}
+
+	merger := &xmlMerger{expand: expand}
+	merger.buf.WriteString("<dds>")
+	for _, file := range strings.Split(url, ";") {
+		file = strings.TrimPrefix(strings.TrimSpace(file), "file://")
+		if file == "" {
+			continue
+		}
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return "", err
+		}
+		// Read from the root of the volume so that includes may refer to
+		// parent directories
+		merger.root = filepath.VolumeName(abs) + string(filepath.Separator)
+		if err := merger.readFile(os.DirFS(merger.root), filepath.ToSlash(abs[len(merger.root):]), nil); err != nil {
+			return "", err
+		}
+	}
+	merger.buf.WriteString("</dds>")
+
+	return merger.buf.String(), nil
+}
+
+// diagnoseConfig is a function to find why the participant configName could
+// not be created from url. It returns the kind of error, such as
+// ErrConfigNotFound, and its cause.
+func diagnoseConfig(configName, url string) (error, error) {
+	document, err := readConfigURL(url, nil)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrConfigNotFound, err
+		}
+		return ErrInvalidXML, err
+	}
+
+	var cfg xmlConfig
+	if err := xml.Unmarshal([]byte(document), &cfg); err != nil {
+		return ErrInvalidXML, err
+	}
+
+	var participants []string
+	for _, library := range cfg.ParticipantLibraries {
+		for _, participant := range library.Participants {
+			participants = append(participants, library.Name+"::"+participant.Name)
+		}
+	}
+	if !slices.Contains(participants, configName) {
+		return ErrParticipantNotFound, fmt.Errorf("available participants: %s", strings.Join(participants, ", "))
+	}
+
+	return ErrInvalidConfig, nil
+}
+
+// entityNames is a function to list the data writers, or data readers, of
+// the participant configName created from url, as "Publisher::DataWriter"
+// names
+func entityNames(configName, url string, writers bool) []string {
+	document, err := readConfigURL(url, nil)
+	if err != nil {
+		return nil
+	}
+	var cfg xmlConfig
+	if err := xml.Unmarshal([]byte(document), &cfg); err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, library := range cfg.ParticipantLibraries {
+		for _, participant := range library.Participants {
+			if library.Name+"::"+participant.Name != configName {
+				continue
+			}
+			if writers {
+				for _, publisher := range participant.Publishers {
+					for _, writer := range publisher.Writers {
+						names = append(names, publisher.Name+"::"+writer.Name)
+					}
+				}
+			} else {
+				for _, subscriber := range participant.Subscribers {
+					for _, reader := range subscriber.Readers {
+						names = append(names, subscriber.Name+"::"+reader.Name)
+					}
+				}
+			}
+		}
+	}
+
+	return names
+}
+
+// applyOverrides is a function to add the QoS policies of options to the
+// participant configName of document, and to its writers and readers. The
+// policies are appended to the <*_qos> element of each entity, after the
+// policies of the XML, or are set in a new one. Partitions of writers and
+// readers are set on their publisher or subscriber. The partitions of
+// options, keyed by "publisher:Name" and "subscriber:Name", are applied
+// last. The content filters of options replace the ones of their readers.
+func applyOverrides(document, configName string, options connectorOptions) (string, error) {
+	overrides := options.qos
+
+	// QoS to apply, by entity: "participant", "publisher:P", "subscriber:S",
+	// "writer:P::W" and "reader:S::R"
+	entities := make(map[string]*config.QoS)
+	entityQoS := func(key string) *config.QoS {
+		if entities[key] == nil {
+			entities[key] = new(config.QoS)
+		}
+		return entities[key]
+	}
+	for _, policy := range overrides.Participant {
+		policy(entityQoS("participant"))
+	}
+	for _, endpoints := range []struct {
+		kind, group string
+		policies    map[string][]qos.Policy
+		notFound    error
+	}{
+		{"writer", "publisher", overrides.Writers, ErrOutputNotFound},
+		{"reader", "subscriber", overrides.Readers, ErrInputNotFound},
+	} {
+		for name, policies := range endpoints.policies {
+			group, _, ok := strings.Cut(name, "::")
+			if !ok {
+				return "", fmt.Errorf("%w %q", endpoints.notFound, name)
+			}
+			q := new(config.QoS)
+			for _, policy := range policies {
+				policy(q)
+			}
+			if q.Partition != nil {
+				entityQoS(endpoints.group + ":" + group).Partition = q.Partition
+				q.Partition = nil
+			}
+			entities[endpoints.kind+":"+name] = q
+		}
+	}
+	for group, names := range options.partitions {
+		entityQoS(group).Partition = &config.Partition{Names: names}
+	}
+	// Content filters to set, by "reader:S::R"
+	filters := make(map[string]string)
+	for name, filter := range options.filters {
+		if _, _, ok := strings.Cut(name, "::"); !ok {
+			return "", fmt.Errorf("%w %q", ErrInputNotFound, name)
+		}
+		element, err := contentFilterXML(name, filter)
+		if err != nil {
+			return "", err
+		}
+		filters["reader:"+name] = element
+	}
+
+	// Elements of the document, from the root to the current one
+	type frame struct {
+		tag, name string
+		// key of the entity the element is, or whose QoS it is, in entities
+		key   string
+		isQoS bool
+		// start and end of the start tag, and whether the element is
+		// self-closing
+		begin       int
+		startEnd    int
+		selfClosing bool
+		hasQoS      bool
+	}
+	qosTags := map[string]string{
+		"domain_participant": "participant_qos",
+		"publisher":          "publisher_qos",
+		"subscriber":         "subscriber_qos",
+		"data_writer":        "datawriter_qos",
+		"data_reader":        "datareader_qos",
+	}
+	// edit replaces length bytes at offset of the document by text
+	type edit struct {
+		offset, length int
+		text           string
+	}
+	var edits []edit
+	participantFound := false
+	found := make(map[string]bool)
+
+	data := []byte(document)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*frame
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		end := int(decoder.InputOffset())
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			current := &frame{
+				tag:         t.Name.Local,
+				begin:       start,
+				startEnd:    end,
+				selfClosing: bytes.HasSuffix(data[start:end], []byte("/>")),
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "name" {
+					current.name = attr.Value
+				}
+			}
+			var parent *frame
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			switch {
+			case current.tag == "domain_participant" && len(stack) == 2 &&
+				parent.tag == "domain_participant_library" && parent.name+"::"+current.name == configName:
+				current.key = "participant"
+				participantFound = true
+			case parent == nil || parent.key == "" || parent.isQoS:
+			case current.tag == qosTags[parent.tag]:
+				current.key = parent.key
+				current.isQoS = true
+				parent.hasQoS = true
+			case parent.key == "participant" && (current.tag == "publisher" || current.tag == "subscriber"):
+				current.key = current.tag + ":" + current.name
+			case parent.tag == "publisher" && current.tag == "data_writer":
+				current.key = "writer:" + parent.name + "::" + current.name
+			case parent.tag == "subscriber" && current.tag == "data_reader":
+				current.key = "reader:" + parent.name + "::" + current.name
+			}
+			stack = append(stack, current)
+
+		case xml.EndElement:
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if current.tag == "content_filter" && len(stack) > 0 {
+				if parent := stack[len(stack)-1]; parent.tag == "data_reader" && filters[parent.key] != "" {
+					// The filter of the options replaces the one of the XML
+					edits = append(edits, edit{offset: current.begin, length: end - current.begin})
+				}
+				continue
+			}
+			q := entities[current.key]
+			filter := ""
+			if !current.isQoS {
+				filter = filters[current.key]
+			}
+			if q == nil && filter == "" {
+				continue
+			}
+
+			if current.isQoS {
+				// The policies are appended to the ones of the element
+				element, err := qosXML(current.tag, q)
+				if err != nil {
+					return "", err
+				}
+				if current.selfClosing {
+					edits = append(edits, edit{offset: current.startEnd - 2, length: 2, text: ">" + element[len(current.tag)+2:]})
+				} else {
+					inner := strings.TrimSuffix(element[len(current.tag)+2:], "</"+current.tag+">")
+					edits = append(edits, edit{offset: start, text: inner})
+				}
+				found[current.key] = true
+				continue
+			}
+
+			children := filter
+			if q != nil && !current.hasQoS {
+				element, err := qosXML(qosTags[current.tag], q)
+				if err != nil {
+					return "", err
+				}
+				children += element
+			}
+			switch {
+			case children == "":
+			case current.selfClosing:
+				edits = append(edits, edit{offset: current.startEnd - 2, length: 2, text: ">" + children + "</" + current.tag + ">"})
+			default:
+				edits = append(edits, edit{offset: current.startEnd, text: children})
+			}
+			found[current.key] = true
+		}
+	}
+
+	if !participantFound {
+		// NewConnector reports that the participant does not exist
+		return document, nil
+	}
+	for key := range filters {
+		if !found[key] {
+			_, name, _ := strings.Cut(key, ":")
+			return "", fmt.Errorf("%w %q", ErrInputNotFound, name)
+		}
+	}
+	for key := range entities {
+		if found[key] {
+			continue
+		}
+		// A publisher or subscriber is missing only if one of its writers
+		// or readers is
+		switch kind, name, _ := strings.Cut(key, ":"); kind {
+		case "writer":
+			return "", fmt.Errorf("%w %q", ErrOutputNotFound, name)
+		case "reader":
+			return "", fmt.Errorf("%w %q", ErrInputNotFound, name)
+		}
+	}
+
+	// Insertions come before the removals at the same offset
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].offset != edits[j].offset {
+			return edits[i].offset < edits[j].offset
+		}
+		return edits[i].length < edits[j].length
+	})
+	var buf strings.Builder
+	previous := 0
+	for _, e := range edits {
+		buf.Write(data[previous:e.offset])
+		buf.WriteString(e.text)
+		previous = e.offset + e.length
+	}
+	buf.Write(data[previous:])
+
+	return buf.String(), nil
+}
+
+// contentFilterXML is a function to marshal the content filter of the reader
+// inputName as a <content_filter> element
+func contentFilterXML(inputName string, filter contentFilter) (string, error) {
+	element := xmlContentFilter{
+		// The name of the content filtered topic must be unique in the
+		// participant
+		Name:       strings.ReplaceAll(inputName, "::", "_") + "_filter",
+		Kind:       "builtin.sql",
+		Expression: filter.expression,
+	}
+	if len(filter.parameters) > 0 {
+		element.Parameters = &struct {
+			Elements []string `xml:"element"`
+		}{filter.parameters}
+	}
+	data, err := xml.Marshal(element)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// qosXML is a function to marshal q as the XML element tag
+func qosXML(tag string, q *config.QoS) (string, error) {
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	if err := encoder.EncodeElement(q, xml.StartElement{Name: xml.Name{Local: tag}}); err != nil {
+		return "", err
+	}
+	if err := encoder.Flush(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// groupPartitions is a function to return the partitions of the publisher, or
+// subscriber, group of the participant configName in document. They are the
+// last partitions set by the QoS of the group, or else by its base profiles,
+// or else by the default profile.
+func groupPartitions(document, configName, group string, subscriber bool) ([]string, error) {
+	var cfg xmlConfig
+	if err := xml.Unmarshal([]byte(document), &cfg); err != nil {
+		return nil, err
+	}
+	profiles, defaultProfile := qosProfiles(cfg)
+
+	// partitionsOf returns the partitions set by qos or its base profiles
+	seen := make(map[*xmlQoSProfile]bool)
+	var partitionsOf func(qos []xmlQoS, baseName string) ([]string, bool)
+	partitionsOf = func(qos []xmlQoS, baseName string) ([]string, bool) {
+		for i := len(qos) - 1; i >= 0; i-- {
+			if partitions := qos[i].Partitions; len(partitions) > 0 {
+				return trimNames(partitions[len(partitions)-1].Names), true
+			}
+		}
+		for i := len(qos) - 1; i >= 0; i-- {
+			if qos[i].BaseName != "" {
+				baseName = qos[i].BaseName
+				break
+			}
+		}
+		profile := profiles[baseName]
+		if profile == nil || seen[profile] {
+			return nil, false
+		}
+		seen[profile] = true
+		if subscriber {
+			return partitionsOf(profile.SubscriberQoS, profile.BaseName)
+		}
+		return partitionsOf(profile.PublisherQoS, profile.BaseName)
+	}
+
+	for _, library := range cfg.ParticipantLibraries {
+		for _, participant := range library.Participants {
+			if library.Name+"::"+participant.Name != configName {
+				continue
+			}
+			groups := participant.Publishers
+			if subscriber {
+				groups = participant.Subscribers
+			}
+			for _, g := range groups {
+				if g.Name != group {
+					continue
+				}
+				qos := g.PublisherQoS
+				if subscriber {
+					qos = g.SubscriberQoS
+				}
+				if partitions, ok := partitionsOf(qos, ""); ok {
+					return partitions, nil
+				}
+				if defaultProfile != nil {
+					qos = defaultProfile.PublisherQoS
+					if subscriber {
+						qos = defaultProfile.SubscriberQoS
+					}
+					partitions, _ := partitionsOf(qos, defaultProfile.BaseName)
+					return partitions, nil
+				}
+				return nil, nil
+			}
+		}
+	}
+
+	if subscriber {
+		return nil, fmt.Errorf("%w %q", ErrInputNotFound, group)
+	}
+	return nil, fmt.Errorf("%w %q", ErrOutputNotFound, group)
+}
+
+// readerHistoryDepth is a function to return the history depth of the data
+// reader inputName of the participant configName in document: the depth of
+// the last history set by the QoS of the reader, or else by its base
+// profiles, or else by the default profile, or else the default depth of 1.
+// It is 0 for a KEEP_ALL history, or when the history is set by a profile
+// that is not in document, such as a builtin one.
+func readerHistoryDepth(document, configName, inputName string) (int, error) {
+	var cfg xmlConfig
+	if err := xml.Unmarshal([]byte(document), &cfg); err != nil {
+		return 0, err
+	}
+	profiles, defaultProfile := qosProfiles(cfg)
+
+	// historyOf returns the history set by qos or its base profiles, and
+	// whether all of them were found
+	seen := make(map[*xmlQoSProfile]bool)
+	var historyOf func(qos []xmlQoS, baseName string) (*config.History, bool)
+	historyOf = func(qos []xmlQoS, baseName string) (*config.History, bool) {
+		for i := len(qos) - 1; i >= 0; i-- {
+			if histories := qos[i].Histories; len(histories) > 0 {
+				return &histories[len(histories)-1], true
+			}
+		}
+		for i := len(qos) - 1; i >= 0; i-- {
+			if qos[i].BaseName != "" {
+				baseName = qos[i].BaseName
+				break
+			}
+		}
+		if baseName == "" {
+			return nil, true
+		}
+		profile := profiles[baseName]
+		if profile == nil {
+			return nil, false
+		}
+		if seen[profile] {
+			return nil, true
+		}
+		seen[profile] = true
+		return historyOf(profile.DataReaderQoS, profile.BaseName)
+	}
+
+	for _, library := range cfg.ParticipantLibraries {
+		for _, participant := range library.Participants {
+			if library.Name+"::"+participant.Name != configName {
+				continue
+			}
+			for _, subscriber := range participant.Subscribers {
+				for _, reader := range subscriber.Readers {
+					if subscriber.Name+"::"+reader.Name != inputName {
+						continue
+					}
+					history, found := historyOf(reader.QoS, "")
+					if history == nil && found && defaultProfile != nil {
+						history, found = historyOf(defaultProfile.DataReaderQoS, defaultProfile.BaseName)
+					}
+					switch {
+					case !found:
+						return 0, nil
+					case history == nil:
+						return 1, nil
+					case strings.Contains(string(history.Kind), "KEEP_ALL"):
+						return 0, nil
+					}
+					return cmp.Or(history.Depth, 1), nil
+				}
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("%w %q", ErrInputNotFound, inputName)
+}
+
+// qosProfiles is a function to return the QoS profiles of cfg by
+// "Library::Profile" name, and its default profile
+func qosProfiles(cfg xmlConfig) (map[string]*xmlQoSProfile, *xmlQoSProfile) {
+	profiles := make(map[string]*xmlQoSProfile)
+	var defaultProfile *xmlQoSProfile
+	for _, library := range cfg.QoSLibraries {
+		for i := range library.Profiles {
+			profile := &library.Profiles[i]
+			profiles[library.Name+"::"+profile.Name] = profile
+			if profile.IsDefaultQoS {
+				defaultProfile = profile
+			}
+		}
+	}
+	return profiles, defaultProfile
+}
+
+// keyMembers is a function to return the key members of the type of the
+// data reader inputName of the participant configName, including the ones of
+// its base types, in the order of the type definition
+func keyMembers(document, configName, inputName string) ([]xmlMember, error) {
+	var cfg xmlConfig
+	if err := xml.Unmarshal([]byte(document), &cfg); err != nil {
+		return nil, err
+	}
+
+	var participant *xmlParticipant
+	topicRef := ""
+	for _, library := range cfg.ParticipantLibraries {
+		for i := range library.Participants {
+			if library.Name+"::"+library.Participants[i].Name != configName {
+				continue
+			}
+			participant = &library.Participants[i]
+			for _, subscriber := range participant.Subscribers {
+				for _, reader := range subscriber.Readers {
+					if subscriber.Name+"::"+reader.Name == inputName {
+						topicRef = reader.TopicRef
+					}
+				}
+			}
+		}
+	}
+	if topicRef == "" {
+		return nil, fmt.Errorf("%w %q", ErrInputNotFound, inputName)
+	}
+
+	// Topics and registered types are looked up in the participant, then in
+	// its domain and the base domains of the domain
+	scopes := []xmlTopics{participant.xmlTopics}
+	domains := make(map[string]*xmlDomain)
+	for _, library := range cfg.DomainLibraries {
+		for i := range library.Domains {
+			domains[library.Name+"::"+library.Domains[i].Name] = &library.Domains[i]
+		}
+	}
+	for domain := domains[participant.DomainRef]; domain != nil && len(scopes) <= len(domains); domain = domains[domain.BaseName] {
+		scopes = append(scopes, domain.xmlTopics)
+	}
+
+	registerTypeRef := ""
+	for _, scope := range scopes {
+		for _, topic := range scope.Topics {
+			if topic.Name == topicRef && registerTypeRef == "" {
+				registerTypeRef = topic.RegisterTypeRef
+			}
+		}
+	}
+	typeRef := ""
+	for _, scope := range scopes {
+		for _, registerType := range scope.RegisterTypes {
+			if registerType.Name == registerTypeRef && typeRef == "" {
+				typeRef = cmp.Or(registerType.TypeRef, registerType.Name)
+			}
+		}
+	}
+	if typeRef == "" {
+		return nil, fmt.Errorf("type of topic %q of input %q not found", topicRef, inputName)
+	}
+
+	// Structs by fully qualified name, e.g. "MyModule::MyType"
+	type xmlStruct struct {
+		// module of the struct, e.g. "MyModule::"
+		scope    string
+		baseType string
+		keys     []xmlMember
+	}
+	structs := make(map[string]xmlStruct)
+	var addModule func(scope string, module xmlModule)
+	addModule = func(scope string, module xmlModule) {
+		for _, definition := range module.Structs {
+			var keys []xmlMember
+			for _, member := range definition.Members {
+				if member.Key {
+					keys = append(keys, member)
+				}
+			}
+			structs[scope+definition.Name] = xmlStruct{scope: scope, baseType: definition.BaseType, keys: keys}
+		}
+		for _, child := range module.Modules {
+			addModule(scope+child.Name+"::", child)
+		}
+	}
+	for _, types := range cfg.Types {
+		addModule("", types)
+	}
+
+	var keys []xmlMember
+	seen := make(map[string]bool)
+	var addKeys func(scope, name string) error
+	addKeys = func(scope, name string) error {
+		// Names are relative to the module of the referring type
+		name = strings.TrimPrefix(name, "::")
+		for {
+			if definition, ok := structs[scope+name]; ok && !seen[scope+name] {
+				seen[scope+name] = true
+				if definition.baseType != "" {
+					if err := addKeys(definition.scope, definition.baseType); err != nil {
+						return err
+					}
+				}
+				keys = append(keys, definition.keys...)
+				return nil
+			}
+			if scope == "" {
+				return fmt.Errorf("type %q of input %q not found", name, inputName)
+			}
+			parent := strings.TrimSuffix(scope, "::")
+			if i := strings.LastIndex(parent, "::"); i >= 0 {
+				scope = parent[:i+2]
+			} else {
+				scope = ""
+			}
+		}
+	}
+	if err := addKeys("", typeRef); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// trimNames is a function to trim the white space around XML element values
+func trimNames(names []string) []string {
+	trimmed := make([]string, len(names))
+	for i, name := range names {
+		trimmed[i] = strings.TrimSpace(name)
+	}
+	return trimmed
+}
+
+// expandConfigVars is a function to replace the $(VAR) references of data by
+// their value in vars, or else in the environment, and the ${env:VAR}
+// references by their value in the environment. The values are escaped for
+// the text or the tag they are in, and comments and CDATA sections are left
+// as they are.
+func expandConfigVars(data []byte, vars map[string]string) ([]byte, error) {
+	undefined := make(map[string]bool)
+	expand := func(segment []byte, escaper *strings.Replacer) []byte {
+		return configVariable.ReplaceAllFunc(segment, func(match []byte) []byte {
+			groups := configVariable.FindSubmatch(match)
+			if name := string(groups[1]); name != "" {
+				if value, ok := vars[name]; ok {
+					return []byte(escaper.Replace(value))
+				}
+				if value, ok := os.LookupEnv(name); ok {
+					return []byte(escaper.Replace(value))
+				}
+				undefined[string(match)] = true
+				return match
+			}
+			if value, ok := os.LookupEnv(string(groups[2])); ok {
+				return []byte(escaper.Replace(value))
+			}
+			undefined[string(match)] = true
+			return match
+		})
+	}
+
+	var expanded []byte
+	for len(data) > 0 {
+		text := bytes.IndexByte(data, '<')
+		if text < 0 {
+			text = len(data)
+		}
+		expanded = append(expanded, expand(data[:text], textEscaper)...)
+		data = data[text:]
+
+		var markup int
+		switch {
+		case len(data) == 0:
+		case bytes.HasPrefix(data, []byte("<!--")):
+			markup = markupEnd(data, "-->")
+			expanded = append(expanded, data[:markup]...)
+		case bytes.HasPrefix(data, []byte("<![CDATA[")):
+			markup = markupEnd(data, "]]>")
+			expanded = append(expanded, data[:markup]...)
+		default:
+			markup = tagEnd(data)
+			expanded = append(expanded, expand(data[:markup], attrEscaper)...)
+		}
+		data = data[markup:]
+	}
+
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined config variables: %s", strings.Join(names, ", "))
+	}
+
+	return expanded, nil
+}
+
+// markupEnd is a function to return the length of the markup at the start of
+// data that ends with end, or of data if it is not terminated
+func markupEnd(data []byte, end string) int {
+	if i := bytes.Index(data, []byte(end)); i >= 0 {
+		return i + len(end)
+	}
+	return len(data)
+}
+
+// tagEnd is a function to return the length of the tag at the start of data,
+// whose attribute values may contain '>'
+func tagEnd(data []byte) int {
+	var quote byte
+	for i, c := range data {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// readFile is a function to write the content of the root element of an XML
+// document to the merged document, with its includes resolved. including
+// lists the documents including this one, to detect cycles.
+func (merger *xmlMerger) readFile(fsys fs.FS, name string, including []string) error {
+	for _, parent := range including {
+		if parent == name {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(including, " -> "), name)
+		}
+	}
+	including = append(including, name)
+	filePath := merger.root + name
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			pathErr.Path = filePath
+		}
+		return err
+	}
+	if merger.expand != nil {
+		if data, err = merger.expand(data); err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+		end := decoder.InputOffset()
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				if t.Name.Local != "dds" {
+					return fmt.Errorf("%s: root element is <%s>, expected <dds>", filePath, t.Name.Local)
+				}
+				continue
+			}
+			if depth == 2 && t.Name.Local == "include" {
+				if err := merger.include(fsys, name, t, including); err != nil {
+					return err
+				}
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("%s: %w", filePath, err)
+				}
+				depth--
+				continue
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				continue
+			}
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			continue
+		}
+
+		if depth > 0 {
+			merger.buf.Write(data[start:end])
+		}
+	}
+
+	return nil
+}
+
+// include is a function to write the document referred to by an
+// <include file="..."/> element of the document name
+func (merger *xmlMerger) include(fsys fs.FS, name string, include xml.StartElement, including []string) error {
+	for _, attr := range include.Attr {
+		if attr.Name.Local == "file" {
+			// Documents read from disk are read from the root of their volume,
+			// so that they may include absolute paths, as with the native
+			// layer. A file system of NewConnectorFS has no such root.
+			if merger.root != "" && filepath.IsAbs(attr.Value) {
+				file := filepath.Clean(attr.Value)
+				if filepath.VolumeName(file)+string(filepath.Separator) != merger.root {
+					return fmt.Errorf("%s: cannot include %s from another volume", merger.root+name, attr.Value)
+				}
+				return merger.readFile(fsys, filepath.ToSlash(file[len(merger.root):]), including)
+			}
+			if path.IsAbs(attr.Value) {
+				return fmt.Errorf("%s: cannot include absolute path %s", merger.root+name, attr.Value)
+			}
+			return merger.readFile(fsys, path.Join(path.Dir(name), attr.Value), including)
+		}
+	}
+
+	return fmt.Errorf("%s: <include> without file attribute", merger.root+name)
+}